@@ -0,0 +1,82 @@
+// Package adminsdk creates Google Workspace Admin SDK clients for a service
+// account with domain-wide delegation, impersonating an administrator.
+//
+// The service account's client ID must be granted the requested scopes in
+// the Admin console under Security > API controls > Domain-wide
+// delegation, and subject must be an administrator allowed to use the
+// APIs. For example:
+//
+//	srv, err := adminsdk.NewDirectoryService(ctx, key, "admin@example.com")
+package adminsdk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/jarodmeng/googleauth/v2"
+	directory "google.golang.org/api/admin/directory/v1"
+	reports "google.golang.org/api/admin/reports/v1"
+	"google.golang.org/api/option"
+)
+
+// DirectoryScopes are the scopes NewDirectoryService requests when given
+// none: read access to users and groups.
+var DirectoryScopes = []string{
+	directory.AdminDirectoryUserReadonlyScope,
+	directory.AdminDirectoryGroupReadonlyScope,
+}
+
+// ReportsScopes are the scopes NewReportsService requests when given none:
+// read access to audit and usage reports.
+var ReportsScopes = []string{
+	reports.AdminReportsAuditReadonlyScope,
+	reports.AdminReportsUsageReadonlyScope,
+}
+
+// Client returns an HTTP client that acts as subject, using the service
+// account key in JSON form. The scopes are given with googleauth.WithScopes
+// among opts, which configure the Authenticator as for
+// googleauth.NewAuthenticator: WithHTTPClient, WithFIPSMode, WithRetryPolicy
+// and the like.
+func Client(ctx context.Context, key []byte, subject string, opts ...googleauth.Option) (*http.Client, error) {
+	if subject == "" {
+		return nil, errors.New("adminsdk: no admin subject given")
+	}
+	opts = append(append([]googleauth.Option{}, opts...),
+		googleauth.WithServiceAccountKey(key), googleauth.WithSubject(subject))
+	a, err := googleauth.NewAuthenticator(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.Client(ctx)
+}
+
+// NewDirectoryService returns a Directory API service acting as subject.
+// DirectoryScopes are requested unless opts include googleauth.WithScopes.
+func NewDirectoryService(ctx context.Context, key []byte, subject string, opts ...googleauth.Option) (*directory.Service, error) {
+	client, err := Client(ctx, key, subject, withDefaultScopes(DirectoryScopes, opts)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return directory.NewService(ctx, option.WithHTTPClient(client))
+}
+
+// NewReportsService returns a Reports API service acting as subject.
+// ReportsScopes are requested unless opts include googleauth.WithScopes.
+func NewReportsService(ctx context.Context, key []byte, subject string, opts ...googleauth.Option) (*reports.Service, error) {
+	client, err := Client(ctx, key, subject, withDefaultScopes(ReportsScopes, opts)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return reports.NewService(ctx, option.WithHTTPClient(client))
+}
+
+// withDefaultScopes puts scopes before opts, so a WithScopes among them
+// replaces them.
+func withDefaultScopes(scopes []string, opts []googleauth.Option) []googleauth.Option {
+	return append([]googleauth.Option{googleauth.WithScopes(scopes...)}, opts...)
+}
@@ -0,0 +1,87 @@
+package adminsdk_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jarodmeng/googleauth/v2"
+	"github.com/jarodmeng/googleauth/v2/adminsdk"
+)
+
+type countingTransport struct {
+	n int32
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.n, 1)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestClient(t *testing.T) {
+	var sub, scope string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/token" {
+			return
+		}
+		parts := strings.Split(r.FormValue("assertion"), ".")
+		if len(parts) == 3 {
+			b, _ := base64.RawURLEncoding.DecodeString(parts[1])
+			var c struct {
+				Sub   string `json:"sub"`
+				Scope string `json:"scope"`
+			}
+			json.Unmarshal(b, &c)
+			sub, scope = c.Sub, c.Scope
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"at","token_type":"Bearer","expires_in":3600}`)
+	}))
+	defer srv.Close()
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   "sa@project.iam.gserviceaccount.com",
+		"private_key_id": "k1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":      srv.URL + "/token",
+	})
+
+	rt := &countingTransport{}
+	ctx := context.Background()
+	c, err := adminsdk.Client(ctx, key, "admin@example.com",
+		googleauth.WithScopes(adminsdk.DirectoryScopes...),
+		googleauth.WithHTTPClient(&http.Client{Transport: rt}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Get(srv.URL + "/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if sub != "admin@example.com" || scope != strings.Join(adminsdk.DirectoryScopes, " ") {
+		t.Fatalf("assertion for sub %q, scope %q", sub, scope)
+	}
+	if atomic.LoadInt32(&rt.n) != 2 {
+		t.Fatalf("%d requests through the HTTP client, want the token and API requests", rt.n)
+	}
+}
@@ -0,0 +1,109 @@
+package googleauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// A URLOpener shows a URL to the user. On mobile platforms it is usually
+// implemented in platform code with a Custom Tab or an
+// ASWebAuthenticationSession.
+type URLOpener interface {
+	OpenURL(url string) error
+}
+
+// AppRedirectReceiver is a CodeReceiver for apps whose OAuth client
+// redirects to a custom URI scheme or an app link. The operating system
+// routes the redirect back to the app, which passes it to HandleRedirect
+// from its deep link handler.
+type AppRedirectReceiver struct {
+	// RedirectURL is the redirect registered for the client. Empty means
+	// the reversed client ID scheme Google assigns to Android and iOS
+	// clients, com.googleusercontent.apps.<id>:/oauth2redirect.
+	RedirectURL string
+	// Opener shows the consent page.
+	Opener URLOpener
+
+	mu      sync.Mutex
+	state   string
+	pending chan codeResult
+}
+
+// ReceiveCode implements CodeReceiver.
+func (r *AppRedirectReceiver) ReceiveCode(ctx context.Context, req *AuthRequest) (string, error) {
+	if r.Opener == nil {
+		return "", errors.New("googleauth: AppRedirectReceiver has no Opener")
+	}
+	req.Config.RedirectURL = r.RedirectURL
+	if req.Config.RedirectURL == "" {
+		req.Config.RedirectURL = reversedClientIDRedirect(req.Config)
+	}
+
+	pending := make(chan codeResult, 1)
+	r.mu.Lock()
+	r.state, r.pending = req.State, pending
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.state, r.pending = "", nil
+		r.mu.Unlock()
+	}()
+
+	if err := r.Opener.OpenURL(req.AuthCodeURL()); err != nil {
+		return "", err
+	}
+
+	select {
+	case res := <-pending:
+		return res.code, res.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// HandleRedirect completes a pending ReceiveCode with the URL the app was
+// opened with. It returns an error if no flow is pending or the URL does
+// not belong to it.
+func (r *AppRedirectReceiver) HandleRedirect(redirect string) error {
+	u, err := url.Parse(redirect)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pending == nil {
+		return errors.New("googleauth: no authorization in progress")
+	}
+	if q.Get("state") != r.state {
+		return errors.New("googleauth: redirect state does not match")
+	}
+
+	var res codeResult
+	switch {
+	case q.Get("error") != "":
+		res.err = fmt.Errorf("googleauth: authorization failed: %s", q.Get("error"))
+	case q.Get("code") != "":
+		res.code = q.Get("code")
+	default:
+		return errors.New("googleauth: redirect has no authorization code")
+	}
+	select {
+	case r.pending <- res:
+	default:
+	}
+
+	return nil
+}
+
+func reversedClientIDRedirect(config *oauth2.Config) string {
+	id := strings.TrimSuffix(config.ClientID, ".apps.googleusercontent.com")
+	return "com.googleusercontent.apps." + id + ":/oauth2redirect"
+}
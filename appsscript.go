@@ -0,0 +1,52 @@
+package googleauth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// AppsScriptScopes returns the OAuth scopes declared in an Apps Script
+// manifest, the project's appsscript.json. The Execution API only accepts
+// tokens that cover every scope the script uses, so the manifest must list
+// them explicitly in oauthScopes; scripts relying on automatic scope
+// detection have no oauthScopes and are rejected.
+func AppsScriptScopes(manifest []byte) ([]string, error) {
+	var m struct {
+		OAuthScopes []string `json:"oauthScopes"`
+	}
+	if err := json.Unmarshal(manifest, &m); err != nil {
+		return nil, fmt.Errorf("googleauth: invalid Apps Script manifest: %v", err)
+	}
+	if len(m.OAuthScopes) == 0 {
+		return nil, errors.New("googleauth: Apps Script manifest has no oauthScopes; " +
+			"list the script's scopes in appsscript.json to call it through the Execution API")
+	}
+
+	return m.OAuthScopes, nil
+}
+
+// WithAppsScript configures the Authenticator for calling a script through
+// the Apps Script Execution API, requesting the scopes declared in the
+// script's manifest file, appsscript.json. The OAuth client must belong to
+// the Google Cloud project the script is associated with.
+//
+// Tokens cached for other scopes cannot call the script; give each script
+// its own token file with WithTokenFile when scripts need different scopes.
+func WithAppsScript(manifestFile string) Option {
+	return func(s *settings) {
+		b, err := ioutil.ReadFile(manifestFile)
+		var scopes []string
+		if err == nil {
+			scopes, err = AppsScriptScopes(b)
+		}
+		if err != nil {
+			if s.err == nil {
+				s.err = err
+			}
+			return
+		}
+		s.scopes = scopes
+	}
+}
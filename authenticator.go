@@ -0,0 +1,533 @@
+package googleauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// An Authenticator obtains OAuth2 tokens for a client, caching them in a
+// TokenStore and running the interactive consent flow when no cached token
+// is available.
+type Authenticator struct {
+	config    *oauth2.Config      // nil when creds is set
+	creds     *google.Credentials // Application Default Credentials, if used
+	store     TokenStore
+	key       string // store key of the token, see profileKey
+	baseKey   string // key before the profile is applied
+	client    *clientInfo
+	flow      Flow
+	receiver  CodeReceiver
+	port      int
+	noBrowser bool
+	prompter  Prompter
+	noPrompt  bool
+	timeout   time.Duration
+	retry     RetryPolicy
+	authOpts  []oauth2.AuthCodeOption // for the authorization URL
+	exchOpts  []oauth2.AuthCodeOption // for the token endpoint
+	maxAge    time.Duration
+	fips      bool
+	issuer    string // OpenID Connect issuer, empty for Google
+	revokeURL string // revocation endpoint, empty if the issuer has none
+	msgs      *Messages
+	hc        *http.Client // for calls to Google, nil for the default
+	logger    Logger
+	events    func(Event)
+	telemetry TelemetrySink
+
+	refreshWindow time.Duration
+	refreshJitter time.Duration
+
+	mu  sync.Mutex
+	tok *oauth2.Token // latest token seen, nil until one is loaded
+
+	refreshMu sync.Mutex // serializes refreshes, see refresh
+
+	eventMu sync.Mutex
+	stage   EventKind // last stage of the consent flow reached
+}
+
+// NewAuthenticator creates an Authenticator configured by opts. A client
+// secret must be supplied with WithSecret or WithSecretFile, unless a
+// service account key is given with WithServiceAccountKey or
+// WithDefaultCredentials or WithContainerDefaults finds Application
+// Default Credentials. No token is fetched until Token, TokenSource or
+// Client is called.
+func NewAuthenticator(ctx context.Context, opts ...Option) (*Authenticator, error) {
+	s := newSettings(opts)
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.container {
+		s.applyContainerDefaults()
+	}
+	if s.openID {
+		s.scopes = withOpenIDScopes(s.scopes)
+	}
+	if s.adc && s.secret == nil && s.secretFile == "" {
+		s.secretFile = os.Getenv(secretFileEnv)
+	}
+
+	a := &Authenticator{
+		flow:      s.flow,
+		receiver:  s.receiver,
+		port:      s.port,
+		noBrowser: s.noBrowser,
+		prompter:  s.prompter,
+		noPrompt:  s.noPrompt,
+		timeout:   s.timeout,
+		retry:     DefaultRetryPolicy,
+		authOpts:  s.authOpts,
+		exchOpts:  s.exchOpts,
+		maxAge:    s.maxAge,
+		fips:      s.fips,
+		issuer:    s.issuer,
+		revokeURL: googleRevokeURL,
+		logger:    s.logger,
+		msgs:      messagesOr(s.messages),
+		events:    s.events,
+		telemetry: s.telemetry,
+
+		refreshWindow: s.refreshWindow,
+		refreshJitter: s.refreshJitter,
+	}
+	if s.retry != nil {
+		a.retry = *s.retry
+	}
+	if a.prompter == nil {
+		a.prompter = &TerminalPrompter{Messages: a.msgs}
+	}
+	if a.events != nil || a.logger != nil {
+		a.prompter = eventPrompter{Prompter: a.prompter, a: a}
+	}
+	hc, dt, err := newHTTPClient(s)
+	if err != nil {
+		return nil, err
+	}
+	a.hc = hc
+	ctx = a.withHTTPClient(ctx)
+
+	if s.saKey != nil {
+		creds, err := serviceAccountCredentials(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		a.creds = creds
+	}
+
+	if (s.container || s.adc) && a.creds == nil {
+		creds, err := google.FindDefaultCredentials(ctx, s.scopes...)
+		if err == nil {
+			a.creds = creds
+			a.logf("googleauth: using Application Default Credentials")
+		} else {
+			a.logf("googleauth: no Application Default Credentials: %v", err)
+		}
+	}
+
+	if a.creds == nil {
+		secret := s.secret
+		if secret == nil && s.secretFile != "" {
+			if err := checkSecretFile(s.secretFile); err != nil {
+				if s.strictPerm {
+					return nil, fmt.Errorf("%w: %v", ErrInsecureSecretFile, err)
+				}
+				a.warnf("googleauth: WARNING: %v", err)
+			}
+			b, err := ioutil.ReadFile(s.secretFile)
+			if err != nil {
+				return nil, err
+			}
+			secret = b
+		}
+		if secret == nil {
+			return nil, fmt.Errorf("%w: no secret given", ErrInvalidSecret)
+		}
+
+		if isExternalAccount(secret) {
+			creds, err := externalAccountCredentials(ctx, secret, s.scopes)
+			if err != nil {
+				return nil, err
+			}
+			a.creds = creds
+			a.logf("googleauth: using external account credentials")
+		} else if err := a.useClientSecret(ctx, s, secret); err != nil {
+			return nil, err
+		}
+	}
+	if dt != nil && a.config != nil {
+		dt.setTokenURL(a.config.Endpoint.TokenURL)
+	}
+
+	if a.store, err = s.tokenStore(); err != nil {
+		return nil, err
+	}
+
+	a.baseKey = s.tokenFile
+	if a.baseKey == "" {
+		if a.config != nil {
+			a.baseKey = a.config.ClientID + ".json"
+		} else {
+			a.baseKey = "default.json"
+		}
+	}
+	a.key = profileKey(s.profile, a.baseKey)
+
+	return a, nil
+}
+
+// useClientSecret configures the Authenticator for the OAuth client of
+// secret.
+func (a *Authenticator) useClientSecret(ctx context.Context, s *settings, secret []byte) error {
+	want, err := s.resolveClientType()
+	if err != nil {
+		return err
+	}
+	a.config, a.client, err = parseSecret(secret, s.scopes, want, s.prefersWeb())
+	if err != nil {
+		return err
+	}
+	if a.client.web {
+		a.logf("googleauth: using the web client %s", a.config.ClientID)
+	} else {
+		a.logf("googleauth: using the installed client %s", a.config.ClientID)
+	}
+	if s.issuer != "" {
+		return a.useIssuer(ctx, s.issuer)
+	}
+
+	return nil
+}
+
+// Token returns a valid token, loading it from the store or running the
+// consent flow, and refreshing it if it has expired. ctx bounds all network
+// calls made on the way, including the code exchange and the refresh.
+func (a *Authenticator) Token(ctx context.Context) (_ *oauth2.Token, err error) {
+	defer a.recoverPanic("Token", &err)
+
+	ts, err := a.TokenSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.Token()
+}
+
+// TokenSource returns a TokenSource that refreshes the cached token as
+// needed. ctx is used for the consent flow and for every refresh.
+func (a *Authenticator) TokenSource(ctx context.Context) (_ oauth2.TokenSource, err error) {
+	defer a.recoverPanic("TokenSource", &err)
+
+	ctx = a.withHTTPClient(ctx)
+	if a.creds != nil {
+		return &trackingSource{a: a, src: a.creds.TokenSource}, nil
+	}
+
+	if _, err := a.storedToken(ctx); err != nil {
+		return nil, err
+	}
+
+	return &refreshingSource{a: a, ctx: ctx}, nil
+}
+
+// Client returns an HTTP client that authorizes requests with the
+// Authenticator's token. The client can be passed to New() function of
+// Google client libraries to create an API service instance.
+func (a *Authenticator) Client(ctx context.Context) (*http.Client, error) {
+	ctx = a.withHTTPClient(ctx)
+	ts, err := a.TokenSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return oauth2.NewClient(ctx, ts), nil
+}
+
+// TokenExpiry returns when the current access token expires, without
+// making any network calls. It returns the zero time if no token has been
+// obtained or cached yet, or if the token never expires. A refresh token,
+// when present, usually outlives the access token.
+func (a *Authenticator) TokenExpiry() time.Time {
+	tok := a.currentToken()
+	if tok == nil {
+		return time.Time{}
+	}
+
+	return tok.Expiry
+}
+
+// Valid reports whether the Authenticator holds an access token that has
+// not expired, without making any network calls.
+func (a *Authenticator) Valid() bool {
+	return a.currentToken().Valid()
+}
+
+// memToken returns the latest token seen, or nil.
+func (a *Authenticator) memToken() *oauth2.Token {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.tok
+}
+
+// currentToken returns the latest token seen, falling back to the store.
+func (a *Authenticator) currentToken() *oauth2.Token {
+	tok := a.memToken()
+	if tok != nil {
+		return tok
+	}
+
+	tok, err := a.storeGet()
+	if err != nil {
+		return nil
+	}
+
+	return tok
+}
+
+func (a *Authenticator) setToken(tok *oauth2.Token) {
+	a.mu.Lock()
+	a.tok = tok
+	a.mu.Unlock()
+}
+
+// trackingSource records every token its source returns on the
+// Authenticator.
+type trackingSource struct {
+	a   *Authenticator
+	src oauth2.TokenSource
+}
+
+func (s *trackingSource) Token() (_ *oauth2.Token, err error) {
+	defer s.a.recoverPanic("Token", &err)
+
+	tok, err := s.src.Token()
+	if err != nil {
+		return nil, err
+	}
+	s.a.setToken(tok)
+
+	return tok, nil
+}
+
+// storedToken returns the token held in memory or the store, running the
+// consent flow if there is none. The token may have expired.
+func (a *Authenticator) storedToken(ctx context.Context) (*oauth2.Token, error) {
+	// The grant in memory may have grown too old since it was loaded.
+	if tok := a.memToken(); tok != nil && a.usable(tok) {
+		return tok, nil
+	}
+
+	tok, err := a.storeGet()
+	if errors.Is(err, ErrCacheKey) {
+		// Consenting again would overwrite a grant the right key can read.
+		return nil, err
+	}
+	if err == nil && a.grantTooOld(tok) {
+		err = errGrantTooOld
+	}
+	if err == nil && a.scopesChanged(tok) {
+		err = errScopesChanged
+	}
+	if err == nil {
+		a.setToken(tok)
+		a.logf("googleauth: using cached token for %q", a.key)
+		return tok, nil
+	}
+
+	a.logf("googleauth: no cached token for %q (%v), starting consent flow", a.key, err)
+
+	tok, unlock, err := a.lockConsent(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tok != nil {
+		a.setToken(tok)
+		a.logf("googleauth: using token for %q obtained by another process", a.key)
+		return tok, nil
+	}
+	defer unlock()
+
+	tok, err = a.authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tok = a.newGrant(tok)
+	err = a.putToken(tok)
+	if err != nil {
+		a.emitFailed(err)
+		return nil, err
+	}
+	a.emit(TokenSaved)
+	a.setToken(tok)
+
+	return tok, nil
+}
+
+// newGrant records when and for which scopes the user consented to tok.
+func (a *Authenticator) newGrant(tok *oauth2.Token) *oauth2.Token {
+	return withExtra(tok, map[string]interface{}{
+		extraGrantedAt: timeNow().Unix(),
+		extraScopes:    strings.Join(a.config.Scopes, " "),
+	})
+}
+
+// authorize obtains a new token interactively, through the configured
+// CodeReceiver if there is one and the configured Flow otherwise.
+func (a *Authenticator) authorize(ctx context.Context) (*oauth2.Token, error) {
+	if a.noPrompt {
+		return nil, ErrConsentRequired
+	}
+	if a.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.timeout)
+		defer cancel()
+	}
+
+	a.emit(FlowStarted)
+	start := time.Now()
+	var tok *oauth2.Token
+	var err error
+	if a.receiver != nil {
+		tok, err = a.authorizeCode(ctx, a.receiver, "")
+		a.report("consent", "receiver", start, err)
+	} else {
+		tok, err = a.runFlow(ctx)
+		a.report("consent", a.flow.String(), start, err)
+	}
+	a.prompter.ShowResult(err)
+	if err != nil {
+		a.emitFailed(err)
+	}
+
+	return tok, err
+}
+
+// authorizeCode runs the authorization code flow through r and exchanges
+// the code for a token. A non-empty redirectURL overrides the one from the
+// client secret.
+func (a *Authenticator) authorizeCode(ctx context.Context, r CodeReceiver, redirectURL string) (*oauth2.Token, error) {
+	config := *a.config
+	if redirectURL != "" {
+		config.RedirectURL = redirectURL
+	}
+	state, err := randomState()
+	if err != nil {
+		return nil, err
+	}
+	req := &AuthRequest{
+		Config:  &config,
+		State:   state,
+		Options: append([]oauth2.AuthCodeOption{oauth2.AccessTypeOffline}, a.authOpts...),
+	}
+	exchangeOpts := a.exchOpts
+	if !a.client.web {
+		// Installed apps cannot keep their secret, so PKCE binds the code
+		// to this flow.
+		verifier := oauth2.GenerateVerifier()
+		req.Options = append(req.Options, oauth2.S256ChallengeOption(verifier))
+		exchangeOpts = append([]oauth2.AuthCodeOption{oauth2.VerifierOption(verifier)}, exchangeOpts...)
+	}
+	code, err := r.ReceiveCode(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if code == "" {
+		return nil, errors.New("googleauth: empty authorization code")
+	}
+	a.emit(CodeReceived)
+	a.prompter.ShowProgress(a.msgs.Exchanging)
+
+	tok, err := a.withRetry(ctx, "code exchange", isTransientExchange, func() (*oauth2.Token, error) {
+		return req.Config.Exchange(ctx, code, exchangeOpts...)
+	})
+	if err == nil {
+		a.logf("googleauth: exchanged authorization code for %q", a.key)
+	}
+
+	return tok, exchangeError("exchange", err)
+}
+
+// randomState returns an unguessable state parameter, which receivers
+// check the redirect against to reject forged callbacks.
+func randomState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+var errGrantTooOld = errors.New("googleauth: grant exceeds maximum age")
+
+// timeNow is the clock grant ages are measured by, for tests to move.
+var timeNow = time.Now
+
+// grantTooOld reports whether tok's grant is older than WithMaxGrantAge
+// allows.
+func (a *Authenticator) grantTooOld(tok *oauth2.Token) bool {
+	if a.maxAge <= 0 {
+		return false
+	}
+	t := grantedAt(tok)
+
+	return t.IsZero() || timeNow().Sub(t) > a.maxAge
+}
+
+// usable reports whether the cached tok may be used without consenting
+// again.
+func (a *Authenticator) usable(tok *oauth2.Token) bool {
+	return !a.grantTooOld(tok) && !a.scopesChanged(tok)
+}
+
+var errScopesChanged = errors.New("googleauth: scopes differ from those granted")
+
+// scopesChanged reports whether tok's grant lacks any of the configured
+// scopes, because one was added or the user declined it, so that the user
+// is asked for consent again. Tokens whose scopes are unknown are kept.
+func (a *Authenticator) scopesChanged(tok *oauth2.Token) bool {
+	granted := tokenScopes(tok)
+	if granted == nil {
+		return false
+	}
+	have := make(map[string]bool)
+	for _, s := range granted {
+		have[canonicalScope(s)] = true
+	}
+	for _, s := range a.config.Scopes {
+		if !have[canonicalScope(s)] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a *Authenticator) logf(format string, v ...interface{}) {
+	if a.logger != nil {
+		a.logger.Printf(format, v...)
+	}
+}
+
+// warnf is like logf but falls back to standard error, for problems the
+// user should hear about even when no Logger is configured.
+func (a *Authenticator) warnf(format string, v ...interface{}) {
+	if a.logger != nil {
+		a.logger.Printf(format, v...)
+		return
+	}
+	fmt.Fprintf(os.Stderr, format+"\n", v...)
+}
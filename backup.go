@@ -0,0 +1,69 @@
+package googleauth
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// previousKey returns the key under which the token stored before the one
+// under key is kept. Like every key the package keeps for itself it
+// starts with a dot, so TokenLister.Keys skips it and it cannot clash
+// with the key of another token.
+func previousKey(key string) string {
+	return ".prev." + key
+}
+
+// hiddenKey reports whether key is one of the package's own, which
+// TokenLister.Keys leaves out.
+func hiddenKey(key string) bool {
+	return strings.HasPrefix(key, ".")
+}
+
+// ErrNoBackup is returned by RollbackToken when no previous token is kept.
+var ErrNoBackup = errors.New("googleauth: no previous token to roll back to")
+
+// putToken stores tok, keeping the token it replaces under the backup key
+// in the same store.
+func (a *Authenticator) putToken(tok *oauth2.Token) error {
+	if old, err := a.storeGet(); err == nil && !sameToken(old, tok) {
+		if err := a.store.Put(previousKey(a.key), old); err != nil {
+			a.logf("googleauth: cannot back up token for %q: %v", a.key, err)
+		}
+	}
+
+	return a.store.Put(a.key, tok)
+}
+
+func sameToken(a, b *oauth2.Token) bool {
+	return a.AccessToken == b.AccessToken && a.RefreshToken == b.RefreshToken
+}
+
+// RollbackToken restores the token stored before the last change, such as
+// after a refresh stored a token that turned out not to work. The replaced
+// token becomes the backup, so a second RollbackToken undoes the first.
+func (a *Authenticator) RollbackToken() error {
+	prev, err := a.store.Get(previousKey(a.key))
+	if errors.Is(err, ErrTokenNotCached) || err == nil && prev == nil {
+		return ErrNoBackup
+	}
+	if err != nil {
+		return err
+	}
+
+	if cur, err := a.storeGet(); err == nil {
+		if err := a.store.Put(previousKey(a.key), cur); err != nil {
+			return err
+		}
+	} else if err := a.store.Delete(previousKey(a.key)); err != nil {
+		return err
+	}
+	if err := a.store.Put(a.key, prev); err != nil {
+		return err
+	}
+	a.setToken(prev)
+	a.logf("googleauth: rolled back token for %q", a.key)
+
+	return nil
+}
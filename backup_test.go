@@ -0,0 +1,56 @@
+package googleauth_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jarodmeng/googleauth/v2"
+	"github.com/jarodmeng/googleauth/v2/googleauthtest"
+)
+
+func TestRollbackToken(t *testing.T) {
+	ctx := context.Background()
+	srv := googleauthtest.NewServer()
+	defer srv.Close()
+	srv.SetRefreshTokenRotation(true)
+	store, err := googleauth.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newAuthenticator(t, srv, store)
+	if err := a.RollbackToken(); !errors.Is(err, googleauth.ErrNoBackup) {
+		t.Fatalf("RollbackToken without a backup = %v, want ErrNoBackup", err)
+	}
+	first, err := a.Token(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := a.RefreshNow(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The backup is no grant of its own.
+	keys, err := store.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keys, []string{tokenFile}) {
+		t.Fatalf("Keys = %q, want only %q", keys, tokenFile)
+	}
+
+	for _, want := range []string{first.RefreshToken, second.RefreshToken} {
+		if err := a.RollbackToken(); err != nil {
+			t.Fatal(err)
+		}
+		tok, err := store.Get(tokenFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.RefreshToken != want {
+			t.Fatalf("after RollbackToken refresh token is %q, want %q", tok.RefreshToken, want)
+		}
+	}
+}
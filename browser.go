@@ -0,0 +1,29 @@
+package googleauth
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/pkg/browser"
+)
+
+// openBrowser opens url in the user's browser.
+func openBrowser(url string) error {
+	if onCrostini() {
+		// Linux apps on ChromeOS have no browser of their own; garcon
+		// hands the URL to the ChromeOS browser.
+		return exec.Command("garcon-url-handler", url).Run()
+	}
+	if inSnap() || inFlatpak() {
+		return openPortal(url)
+	}
+
+	return browser.OpenURL(url)
+}
+
+// onCrostini reports whether the program runs in the Linux container of
+// ChromeOS.
+func onCrostini() bool {
+	_, err := os.Stat("/dev/.cros_milestone")
+	return err == nil
+}
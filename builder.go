@@ -0,0 +1,244 @@
+package googleauth
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// A Builder assembles an Authenticator one setting at a time. It is
+// equivalent to passing the corresponding options to NewAuthenticator and
+// tends to read better for larger configurations:
+//
+//	auth, err := googleauth.New().
+//		SecretFile("client_secret.json").
+//		Scopes(drive.DriveScope).
+//		Flow(googleauth.FlowLoopback).
+//		Build(ctx)
+type Builder struct {
+	opts []Option
+}
+
+// New returns an empty Builder.
+func New() *Builder {
+	return &Builder{}
+}
+
+// With adds options that have no dedicated Builder method.
+func (b *Builder) With(opts ...Option) *Builder {
+	b.opts = append(b.opts, opts...)
+	return b
+}
+
+// Secret is equivalent to WithSecret.
+func (b *Builder) Secret(secret []byte) *Builder {
+	return b.With(WithSecret(secret))
+}
+
+// SecretFile is equivalent to WithSecretFile.
+func (b *Builder) SecretFile(file string) *Builder {
+	return b.With(WithSecretFile(file))
+}
+
+// Scopes is equivalent to WithScopes.
+func (b *Builder) Scopes(scopes ...string) *Builder {
+	return b.With(WithScopes(scopes...))
+}
+
+// OpenID is equivalent to WithOpenID.
+func (b *Builder) OpenID() *Builder {
+	return b.With(WithOpenID())
+}
+
+// TokenFile is equivalent to WithTokenFile.
+func (b *Builder) TokenFile(name string) *Builder {
+	return b.With(WithTokenFile(name))
+}
+
+// Profile is equivalent to WithProfile.
+func (b *Builder) Profile(name string) *Builder {
+	return b.With(WithProfile(name))
+}
+
+// CacheDir is equivalent to WithCacheDir.
+func (b *Builder) CacheDir(dir string) *Builder {
+	return b.With(WithCacheDir(dir))
+}
+
+// Store is equivalent to WithTokenStore.
+func (b *Builder) Store(store TokenStore) *Builder {
+	return b.With(WithTokenStore(store))
+}
+
+// NoCache is equivalent to WithoutCache.
+func (b *Builder) NoCache() *Builder {
+	return b.With(WithoutCache())
+}
+
+// NoInteraction is equivalent to WithoutInteraction.
+func (b *Builder) NoInteraction() *Builder {
+	return b.With(WithoutInteraction())
+}
+
+// ContainerDefaults is equivalent to WithContainerDefaults.
+func (b *Builder) ContainerDefaults() *Builder {
+	return b.With(WithContainerDefaults())
+}
+
+// DefaultCredentials is equivalent to WithDefaultCredentials.
+func (b *Builder) DefaultCredentials() *Builder {
+	return b.With(WithDefaultCredentials())
+}
+
+// MaxGrantAge is equivalent to WithMaxGrantAge.
+func (b *Builder) MaxGrantAge(d time.Duration) *Builder {
+	return b.With(WithMaxGrantAge(d))
+}
+
+// HTTPClient is equivalent to WithHTTPClient.
+func (b *Builder) HTTPClient(c *http.Client) *Builder {
+	return b.With(WithHTTPClient(c))
+}
+
+// FIPSMode is equivalent to WithFIPSMode.
+func (b *Builder) FIPSMode() *Builder {
+	return b.With(WithFIPSMode())
+}
+
+// DeviceCertificate is equivalent to WithDeviceCertificate.
+func (b *Builder) DeviceCertificate() *Builder {
+	return b.With(WithDeviceCertificate())
+}
+
+// AppsScript is equivalent to WithAppsScript.
+func (b *Builder) AppsScript(manifestFile string) *Builder {
+	return b.With(WithAppsScript(manifestFile))
+}
+
+// Issuer is equivalent to WithIssuer.
+func (b *Builder) Issuer(issuer string) *Builder {
+	return b.With(WithIssuer(issuer))
+}
+
+// Language is equivalent to WithLanguage.
+func (b *Builder) Language(lang string) *Builder {
+	return b.With(WithLanguage(lang))
+}
+
+// Messages is equivalent to WithMessages.
+func (b *Builder) Messages(m *Messages) *Builder {
+	return b.With(WithMessages(m))
+}
+
+// EventHandler is equivalent to WithEventHandler.
+func (b *Builder) EventHandler(h func(Event)) *Builder {
+	return b.With(WithEventHandler(h))
+}
+
+// Telemetry is equivalent to WithTelemetry.
+func (b *Builder) Telemetry(sink TelemetrySink) *Builder {
+	return b.With(WithTelemetry(sink))
+}
+
+// RefreshWindow is equivalent to WithRefreshWindow.
+func (b *Builder) RefreshWindow(window time.Duration) *Builder {
+	return b.With(WithRefreshWindow(window))
+}
+
+// RefreshJitter is equivalent to WithRefreshJitter.
+func (b *Builder) RefreshJitter(jitter time.Duration) *Builder {
+	return b.With(WithRefreshJitter(jitter))
+}
+
+// ClientType is equivalent to WithClientType.
+func (b *Builder) ClientType(t ClientType) *Builder {
+	return b.With(WithClientType(t))
+}
+
+// ServiceAccountKey is equivalent to WithServiceAccountKey.
+func (b *Builder) ServiceAccountKey(key []byte) *Builder {
+	return b.With(WithServiceAccountKey(key))
+}
+
+// Subject is equivalent to WithSubject.
+func (b *Builder) Subject(subject string) *Builder {
+	return b.With(WithSubject(subject))
+}
+
+// CacheKey is equivalent to WithCacheKey.
+func (b *Builder) CacheKey(key []byte) *Builder {
+	return b.With(WithCacheKey(key))
+}
+
+// CachePassphrase is equivalent to WithCachePassphrase.
+func (b *Builder) CachePassphrase(passphrase string) *Builder {
+	return b.With(WithCachePassphrase(passphrase))
+}
+
+// Flow is equivalent to WithFlow.
+func (b *Builder) Flow(f Flow) *Builder {
+	return b.With(WithFlow(f))
+}
+
+// CodeReceiver is equivalent to WithCodeReceiver.
+func (b *Builder) CodeReceiver(r CodeReceiver) *Builder {
+	return b.With(WithCodeReceiver(r))
+}
+
+// Prompter is equivalent to WithPrompter.
+func (b *Builder) Prompter(p Prompter) *Builder {
+	return b.With(WithPrompter(p))
+}
+
+// AuthHandler is equivalent to WithAuthHandler.
+func (b *Builder) AuthHandler(h AuthCodeHandler) *Builder {
+	return b.With(WithAuthHandler(h))
+}
+
+// RedirectPort is equivalent to WithRedirectPort.
+func (b *Builder) RedirectPort(port int) *Builder {
+	return b.With(WithRedirectPort(port))
+}
+
+// Browser is equivalent to WithBrowser.
+func (b *Builder) Browser(open bool) *Builder {
+	return b.With(WithBrowser(open))
+}
+
+// RetryPolicy is equivalent to WithRetryPolicy.
+func (b *Builder) RetryPolicy(p RetryPolicy) *Builder {
+	return b.With(WithRetryPolicy(p))
+}
+
+// FlowTimeout is equivalent to WithFlowTimeout.
+func (b *Builder) FlowTimeout(d time.Duration) *Builder {
+	return b.With(WithFlowTimeout(d))
+}
+
+// IncrementalAuth is equivalent to WithIncrementalAuth.
+func (b *Builder) IncrementalAuth() *Builder {
+	return b.With(WithIncrementalAuth())
+}
+
+// AuthCodeOptions is equivalent to WithAuthCodeOptions.
+func (b *Builder) AuthCodeOptions(opts ...oauth2.AuthCodeOption) *Builder {
+	return b.With(WithAuthCodeOptions(opts...))
+}
+
+// ExchangeOptions is equivalent to WithExchangeOptions.
+func (b *Builder) ExchangeOptions(opts ...oauth2.AuthCodeOption) *Builder {
+	return b.With(WithExchangeOptions(opts...))
+}
+
+// Logger is equivalent to WithLogger.
+func (b *Builder) Logger(l Logger) *Builder {
+	return b.With(WithLogger(l))
+}
+
+// Build creates the Authenticator. It is equivalent to calling
+// NewAuthenticator with the collected options.
+func (b *Builder) Build(ctx context.Context) (*Authenticator, error) {
+	return NewAuthenticator(ctx, b.opts...)
+}
@@ -0,0 +1,18 @@
+package googleauth
+
+import "os"
+
+// cacheDirEnv names the environment variable that overrides the default
+// cache directory.
+const cacheDirEnv = "GOOGLEAUTH_CACHE_DIR"
+
+// defaultCacheDir returns the directory of the default FileStore. It is
+// only consulted when no store or cache directory is configured, so
+// programs that configure one never depend on a home directory.
+func defaultCacheDir() (string, error) {
+	if dir := os.Getenv(cacheDirEnv); dir != "" {
+		return dir, nil
+	}
+
+	return platformCacheDir()
+}
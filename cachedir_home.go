@@ -0,0 +1,38 @@
+//go:build !android && !ios
+
+package googleauth
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func platformCacheDir() (string, error) {
+	if dir := sandboxCacheDir(); dir != "" {
+		return dir, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("googleauth: cannot locate the token cache, "+
+			"set %s or use WithCacheDir: %v", cacheDirEnv, err)
+	}
+
+	return filepath.Join(dir, "googleauth"), nil
+}
+
+// legacyCacheDir returns ~/.credentials, where tokens were cached by
+// default before the platform's configuration directory was used, or ""
+// when the default directory is overridden or confined anyway.
+func legacyCacheDir() string {
+	if os.Getenv(cacheDirEnv) != "" || sandboxCacheDir() != "" {
+		return ""
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".credentials")
+}
@@ -0,0 +1,24 @@
+//go:build android || ios
+
+package googleauth
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+func platformCacheDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("googleauth: no default cache directory on %s, "+
+			"use WithCacheDir with the app's files directory: %v", runtime.GOOS, err)
+	}
+
+	return filepath.Join(dir, "googleauth"), nil
+}
+
+func legacyCacheDir() string {
+	return ""
+}
@@ -0,0 +1,172 @@
+package googleauth
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// cacheMagic starts every encrypted token file, and names its format
+// version.
+const cacheMagic = "googleauth-enc-v1\n"
+
+// cacheSaltFile holds the salt passphrase keys are derived with. Its name
+// starts with a dot so FileStore.Keys skips it.
+const cacheSaltFile = ".salt"
+
+// WithCacheKey encrypts the tokens of the FileStore cache with AES-256-GCM
+// under key, which must be 32 bytes long. Tokens already cached in
+// plaintext are still read and are encrypted the first time they are
+// loaded. A FileStore given with WithTokenStore is not changed; the
+// Authenticator reads and writes its directory through an encrypting copy.
+func WithCacheKey(key []byte) Option {
+	return func(s *settings) {
+		s.cacheKey = func(string) ([]byte, error) { return key, nil }
+	}
+}
+
+// WithCachePassphrase is like WithCacheKey with a key derived from
+// passphrase by PBKDF2-SHA256 and a random salt kept in the cache
+// directory.
+func WithCachePassphrase(passphrase string) Option {
+	return func(s *settings) {
+		s.cacheKey = func(dir string) ([]byte, error) {
+			salt, err := cacheSalt(dir)
+			if err != nil {
+				return nil, err
+			}
+
+			return pbkdf2.Key([]byte(passphrase), salt, 600000, 32, sha256.New), nil
+		}
+	}
+}
+
+const (
+	saltSize = 16
+	// saltWait bounds the wait for another process to finish writing the
+	// salt it has just created.
+	saltWait = 2 * time.Second
+)
+
+// cacheSalt returns the salt kept in dir, creating it on first use. Only
+// one process can create it; the others read what it wrote, so they all
+// derive the same key. A salt file of the wrong size is an error rather
+// than replaced, as every token encrypted under it would be lost.
+func cacheSalt(dir string) ([]byte, error) {
+	path := filepath.Join(dir, cacheSaltFile)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if os.IsExist(err) {
+		return readSalt(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err = rand.Read(salt); err == nil {
+		_, err = f.Write(salt)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	return salt, nil
+}
+
+// readSalt reads the salt file at path, waiting for a process that
+// created it to write it.
+func readSalt(path string) ([]byte, error) {
+	deadline := time.Now().Add(saltWait)
+	for {
+		salt, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if len(salt) == saltSize {
+			return salt, nil
+		}
+		if len(salt) > saltSize || time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: salt file %s is %d bytes, not %d", ErrCacheKey, path, len(salt), saltSize)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// encryptCache returns a FileStore on the directory of store that
+// encrypts its tokens with the key cacheKey returns for the directory.
+// store itself is left alone, as other Authenticators may share it.
+func encryptCache(store TokenStore, cacheKey func(dir string) ([]byte, error)) (*FileStore, error) {
+	fs, ok := store.(*FileStore)
+	if !ok {
+		return nil, fmt.Errorf("googleauth: cache encryption needs a FileStore, not %T", store)
+	}
+	key, err := cacheKey(fs.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("%w: key is %d bytes, not 32", ErrCacheKey, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileStore{Dir: fs.Dir, aead: aead, legacyDir: fs.legacyDir}, nil
+}
+
+// seal encrypts b, the stored form of the token under key. The key is
+// authenticated too, so encrypted files cannot be swapped.
+func (s *FileStore) seal(key string, b []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(cacheMagic)
+	buf.Write(nonce)
+	buf.Write(s.aead.Seal(nil, nonce, b, []byte(cacheMagic+key)))
+
+	return buf.Bytes(), nil
+}
+
+// open reverses seal. The second result reports whether b was encrypted.
+func (s *FileStore) open(key string, b []byte) ([]byte, bool, error) {
+	if !bytes.HasPrefix(b, []byte(cacheMagic)) {
+		return b, false, nil
+	}
+	if s.aead == nil {
+		return nil, true, fmt.Errorf("%w: %q is encrypted", ErrCacheKey, key)
+	}
+	b = b[len(cacheMagic):]
+	n := s.aead.NonceSize()
+	if len(b) < n {
+		return nil, true, ErrCacheKey
+	}
+	plain, err := s.aead.Open(nil, b[:n], b[n:], []byte(cacheMagic+key))
+	if err != nil {
+		return nil, true, ErrCacheKey
+	}
+
+	return plain, true, nil
+}
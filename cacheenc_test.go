@@ -0,0 +1,81 @@
+package googleauth
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestCacheSaltConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	salts := make([][]byte, 8)
+	errs := make([]error, len(salts))
+	var wg sync.WaitGroup
+	for i := range salts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			salts[i], errs[i] = cacheSalt(dir)
+		}(i)
+	}
+	wg.Wait()
+	for i := range salts {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if !bytes.Equal(salts[i], salts[0]) {
+			t.Fatalf("salt %d differs from salt 0", i)
+		}
+	}
+}
+
+func TestCacheSaltMalformed(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, cacheSaltFile)
+	if err := ioutil.WriteFile(path, []byte("too long for a salt file"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cacheSalt(dir); !errors.Is(err, ErrCacheKey) {
+		t.Fatalf("cacheSalt = %v, want ErrCacheKey", err)
+	}
+	if b, _ := ioutil.ReadFile(path); string(b) != "too long for a salt file" {
+		t.Fatal("malformed salt file was replaced")
+	}
+}
+
+func TestEncryptCache(t *testing.T) {
+	plain, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := bytes.Repeat([]byte{1}, 32)
+	enc, err := encryptCache(plain, func(string) ([]byte, error) { return key, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.aead != nil {
+		t.Fatal("encryptCache changed the caller's store")
+	}
+	if err := enc.Put("k", &oauth2.Token{AccessToken: "at"}); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(enc.path("k")); bytes.Contains(b, []byte(`"at"`)) {
+		t.Fatal("token stored in plaintext")
+	}
+	if _, err := plain.Get("k"); !errors.Is(err, ErrCacheKey) {
+		t.Fatalf("Get without key = %v, want ErrCacheKey", err)
+	}
+	other, _ := encryptCache(plain, func(string) ([]byte, error) { return bytes.Repeat([]byte{2}, 32), nil })
+	if _, err := other.Get("k"); !errors.Is(err, ErrCacheKey) {
+		t.Fatalf("Get with wrong key = %v, want ErrCacheKey", err)
+	}
+	tok, err := enc.Get("k")
+	if err != nil || tok.AccessToken != "at" {
+		t.Fatalf("Get = %v, %v", tok, err)
+	}
+}
@@ -1,135 +1,105 @@
 // Package googleauth, given a client id and secret, gets/stores/refreshes an
 // OAuth2.0 token with Google. It uses the context/config/token to create an
 // http client ready to be passed to New() to create API service instances.
+//
+// CreateClient and CreateClientFromFile cover the common case. Programs that
+// need more control pass options to NewClient, or build an Authenticator
+// with NewAuthenticator.
+//
+// This is version 2 of the module, imported as
+// github.com/jarodmeng/googleauth/v2. Calls to CreateClient and
+// CreateClientFromFile written for version 1 compile unchanged, so
+// upgrading only changes the import path.
 package googleauth
 
 import (
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
+	"context"
 	"net/http"
-	"net/url"
-	"os"
-	"os/user"
-	"path/filepath"
 
-	"github.com/pkg/browser"
-
-	"golang.org/x/net/context"
 	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 )
 
-func tokenCacheFile(tokenFile string) (string, error) {
-	usr, err := user.Current()
-	if err != nil {
-		return "", err
+// NewClient creates an HTTP client for the OAuth client secret, configured
+// by opts. A nil secret leaves it to opts, such as WithSecretFile. It is
+// shorthand for NewAuthenticator followed by Authenticator.Client.
+func NewClient(ctx context.Context, secret []byte, opts ...Option) (*http.Client, error) {
+	if secret != nil {
+		opts = append([]Option{WithSecret(secret)}, opts...)
 	}
-	tokenCacheDir := filepath.Join(usr.HomeDir, ".credentials")
-	os.MkdirAll(tokenCacheDir, 0700)
-
-	return filepath.Join(tokenCacheDir, url.QueryEscape(tokenFile)), nil
-}
-
-func tokenFromFile(file string) (*oauth2.Token, error) {
-	f, err := os.Open(file)
-	defer f.Close()
+	a, err := NewAuthenticator(ctx, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	t := &oauth2.Token{}
-	err = json.NewDecoder(f).Decode(t)
-	if err != nil {
-		return nil, err
-	}
-
-	return t, nil
+	return a.Client(ctx)
 }
 
-func getTokenFromWeb(config *oauth2.Config) (*oauth2.Token, error) {
-	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
-	fmt.Println("Type the authorization code: ")
-	err := browser.OpenURL(authURL)
-	if err != nil {
-		fmt.Printf("Go to the following link in your browser then type the "+
-			"authorization code: \n%v\n", authURL)
+// NewTokenSource is like NewClient but returns the token source, for
+// libraries that take one, such as option.WithTokenSource of
+// google.golang.org/api or gRPC per-RPC credentials through
+// oauth.TokenSource. The current token is returned by its Token method.
+func NewTokenSource(ctx context.Context, secret []byte, opts ...Option) (oauth2.TokenSource, error) {
+	if secret != nil {
+		opts = append([]Option{WithSecret(secret)}, opts...)
 	}
-	var code string
-	if _, err = fmt.Scan(&code); err != nil {
-		return nil, err
-	}
-
-	tok, err := config.Exchange(oauth2.NoContext, code)
+	a, err := NewAuthenticator(ctx, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	return tok, nil
+	return a.TokenSource(ctx)
 }
 
-func saveToken(file string, token *oauth2.Token) error {
-	f, err := os.Create(file)
-	defer f.Close()
-	if err != nil {
-		return err
-	}
-
-	err = json.NewEncoder(f).Encode(token)
-	if err != nil {
-		return err
-	}
-
-	return nil
+// CreateTokenSource is like CreateClientContext but returns the token
+// source. See NewTokenSource.
+func CreateTokenSource(ctx context.Context, secret []byte, tokenFile string, scopes ...string) (oauth2.TokenSource, error) {
+	return NewTokenSource(ctx, secret, WithTokenFile(tokenFile), WithScopes(scopes...))
 }
 
-func getClient(ctx context.Context, config *oauth2.Config, tokenFile string) (*http.Client, error) {
-	cacheFile, err := tokenCacheFile(tokenFile)
-	if err != nil {
-		return nil, err
-	}
-
-	tok, err := tokenFromFile(cacheFile)
-	if err != nil {
-		tok, err = getTokenFromWeb(config)
-		if err != nil {
-			return nil, err
-		}
-		err = saveToken(cacheFile, tok)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	return config.Client(ctx, tok), nil
-}
-
-// CreateClientFromFile uses a secret file, a token file and a scope
-// string to create an HTTP client. The HTTP client can be passed to New()
+// CreateClientFromFileContext uses a secret file, a token file and scopes
+// to create an HTTP client. The HTTP client can be passed to New()
 // function of Google client libraries to create an API service instance.
-func CreateClientFromFile(secretFile string, tokenFile string, scope string) (*http.Client, error) {
-	b, err := ioutil.ReadFile(secretFile)
-	if err != nil {
-		return nil, err
-	}
-
-	return CreateClient(b, tokenFile, scope)
+//
+// ctx is used for the authorization code exchange and for every subsequent
+// token refresh made by the returned client.
+func CreateClientFromFileContext(ctx context.Context, secretFile string, tokenFile string, scopes ...string) (*http.Client, error) {
+	return NewClient(ctx, nil, WithSecretFile(secretFile),
+		WithTokenFile(tokenFile), WithScopes(scopes...))
 }
 
-// CreateClient takes a byte secret, a token file name and a scope to create an
-// HTTP client.
-func CreateClient(secret []byte, tokenFile string, scope string) (*http.Client, error) {
-	ctx := context.Background()
+// CreateClientContext takes a byte secret, a token file name and scopes to
+// create an HTTP client. ctx is used for the authorization code exchange and
+// for every subsequent token refresh made by the returned client.
+//
+// The scopes are recorded with the cached token; if a later call asks for
+// a scope the token was not granted, the user is asked for consent again.
+// The secret may also be an external_account configuration, see
+// WithSecret.
+func CreateClientContext(ctx context.Context, secret []byte, tokenFile string, scopes ...string) (*http.Client, error) {
+	return NewClient(ctx, secret, WithTokenFile(tokenFile), WithScopes(scopes...))
+}
 
-	config, err := google.ConfigFromJSON(secret, scope)
-	if err != nil {
-		return nil, err
-	}
+// CreateDeviceClient is like CreateClientContext but always uses the
+// device flow: the user is shown a short code and an address to enter it
+// at, on any device, and the token endpoint is polled until they approve.
+// It suits servers reached over SSH, where no browser can be opened. The
+// secret must belong to a "TVs and Limited Input devices" client.
+func CreateDeviceClient(ctx context.Context, secret []byte, tokenFile string, scopes ...string) (*http.Client, error) {
+	return NewClient(ctx, secret, WithTokenFile(tokenFile), WithScopes(scopes...),
+		WithFlow(FlowDevice))
+}
 
-	client, err := getClient(ctx, config, tokenFile)
-	if err != nil {
-		return nil, err
-	}
+// CreateClientFromFile is like CreateClientFromFileContext but uses
+// context.Background().
+//
+// Deprecated: Use CreateClientFromFileContext.
+func CreateClientFromFile(secretFile string, tokenFile string, scopes ...string) (*http.Client, error) {
+	return CreateClientFromFileContext(context.Background(), secretFile, tokenFile, scopes...)
+}
 
-	return client, nil
+// CreateClient is like CreateClientContext but uses context.Background().
+//
+// Deprecated: Use CreateClientContext.
+func CreateClient(secret []byte, tokenFile string, scopes ...string) (*http.Client, error) {
+	return CreateClientContext(context.Background(), secret, tokenFile, scopes...)
 }
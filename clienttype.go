@@ -0,0 +1,80 @@
+package googleauth
+
+import (
+	"fmt"
+	"os"
+)
+
+// clientTypeEnv names the environment variable that selects the client
+// type, "installed" or "web", when WithClientType doesn't.
+const clientTypeEnv = "GOOGLEAUTH_CLIENT_TYPE"
+
+// ClientType selects which entry of a client secret is used when it holds
+// both an "installed" (Desktop app) and a "web" client.
+type ClientType int
+
+const (
+	// ClientAuto uses $GOOGLEAUTH_CLIENT_TYPE if set. Otherwise the
+	// installed client is used with the built-in flows and receivers, and
+	// the web client with any other CodeReceiver, which presumably runs
+	// on a web server.
+	ClientAuto ClientType = iota
+	// ClientInstalled uses the installed client, as the loopback, device
+	// and paste flows require.
+	ClientInstalled
+	// ClientWeb uses the web client, for server-side flows through a
+	// CodeReceiver or a WebFlow.
+	ClientWeb
+)
+
+func (t ClientType) String() string {
+	switch t {
+	case ClientAuto:
+		return "auto"
+	case ClientInstalled:
+		return "installed"
+	case ClientWeb:
+		return "web"
+	}
+
+	return fmt.Sprintf("ClientType(%d)", int(t))
+}
+
+// WithClientType selects the client from a client secret holding both an
+// installed and a web client. A secret lacking the selected client is an
+// ErrUnsupportedClient error.
+func WithClientType(t ClientType) Option {
+	return func(s *settings) {
+		s.clientType = t
+	}
+}
+
+// resolveClientType returns the client type the settings call for, or
+// ClientAuto if either will do.
+func (s *settings) resolveClientType() (ClientType, error) {
+	if s.clientType != ClientAuto {
+		return s.clientType, nil
+	}
+	switch v := os.Getenv(clientTypeEnv); v {
+	case "":
+	case "installed":
+		return ClientInstalled, nil
+	case "web":
+		return ClientWeb, nil
+	default:
+		return ClientAuto, fmt.Errorf("googleauth: %s must be \"installed\" or \"web\", not %q", clientTypeEnv, v)
+	}
+
+	return ClientAuto, nil
+}
+
+// prefersWeb reports whether, given both clients, the configured flow is
+// better served by the web client.
+func (s *settings) prefersWeb() bool {
+	switch s.receiver.(type) {
+	case nil, *LoopbackReceiver, *PasteReceiver, *EmbeddedBrowserReceiver, *AppRedirectReceiver, *handlerReceiver:
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,267 @@
+// Command googleauth runs googleauth maintenance tasks from the shell.
+//
+// Usage:
+//
+//	googleauth login [flags]
+//	googleauth status [flags]
+//	googleauth refresh [flags]
+//	googleauth revoke [flags]
+//	googleauth rotate-key [-admin-key file] keyfile
+//
+// login runs the consent flow, unless a usable token is cached, so that a
+// machine can be authorized before the programs using the token run.
+// status shows the cached token's account, scopes and expiry, and whether
+// its refresh token still works. refresh renews the access token. revoke
+// revokes the grant with Google and deletes it. These commands take:
+//
+//	-secret file      client secret file (default $GOOGLEAUTH_SECRET_FILE)
+//	-scopes list      comma-separated scope URLs or short names
+//	-token-file name  key the token is cached under
+//	-cache-dir dir    directory of the token cache
+//	-profile name     profile the token belongs to
+//
+// The short names are openid, email and profile, and those of the
+// https://www.googleapis.com/auth/ scopes drive, drive.readonly,
+// drive.file, calendar, calendar.readonly, gmail.readonly, gmail.send,
+// gmail.modify, bigquery and cloud-platform, with sheets, docs and slides
+// (and their .readonly forms) standing for spreadsheets, documents and
+// presentations. Other scopes are given as URLs.
+//
+// rotate-key replaces the service account key in keyfile with a new one,
+// deleting the old key once the new one works. The IAM calls are made with
+// the key being rotated unless -admin-key names another service account
+// key with the Service Account Key Admin role.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/jarodmeng/googleauth/v2"
+	"golang.org/x/oauth2/google"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: googleauth login|status|refresh|revoke [-secret file] [-scopes list] [-token-file name] [-cache-dir dir] [-profile name]")
+	fmt.Fprintln(os.Stderr, "       googleauth rotate-key [-admin-key file] keyfile")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var err error
+	switch os.Args[1] {
+	case "login":
+		err = login(ctx, os.Args[2:])
+	case "status":
+		err = status(ctx, os.Args[2:])
+	case "refresh":
+		err = refresh(ctx, os.Args[2:])
+	case "revoke":
+		err = revoke(ctx, os.Args[2:])
+	case "rotate-key":
+		err = rotateKey(ctx, os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		stop()
+		os.Exit(1)
+	}
+}
+
+// authenticator parses the flags common to the token commands and
+// returns the Authenticator they describe.
+func authenticator(ctx context.Context, name string, args []string, interactive bool) (*googleauth.Authenticator, error) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	secret := fs.String("secret", os.Getenv("GOOGLEAUTH_SECRET_FILE"), "client secret file")
+	scopes := fs.String("scopes", "", "comma-separated scopes")
+	tokenFile := fs.String("token-file", "", "key the token is cached under")
+	cacheDir := fs.String("cache-dir", "", "directory of the token cache")
+	profile := fs.String("profile", "", "profile the token belongs to")
+	fs.Parse(args)
+	if fs.NArg() != 0 || *secret == "" {
+		usage()
+	}
+	scopeList, err := parseScopes(*scopes)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		usage()
+	}
+
+	opts := []googleauth.Option{
+		googleauth.WithSecretFile(*secret),
+		googleauth.WithScopes(scopeList...),
+		googleauth.WithTokenFile(*tokenFile),
+		googleauth.WithCacheDir(*cacheDir),
+		googleauth.WithProfile(*profile),
+	}
+	if !interactive {
+		opts = append(opts, googleauth.WithoutInteraction())
+	}
+
+	return googleauth.NewAuthenticator(ctx, opts...)
+}
+
+// shortScopes are the short names -scopes accepts, and the scopes they
+// stand for. The names don't always match the scope's, as with sheets.
+var shortScopes = map[string]string{
+	"openid":            "openid",
+	"email":             "email",
+	"profile":           "profile",
+	"drive":             "https://www.googleapis.com/auth/drive",
+	"drive.readonly":    "https://www.googleapis.com/auth/drive.readonly",
+	"drive.file":        "https://www.googleapis.com/auth/drive.file",
+	"sheets":            "https://www.googleapis.com/auth/spreadsheets",
+	"sheets.readonly":   "https://www.googleapis.com/auth/spreadsheets.readonly",
+	"docs":              "https://www.googleapis.com/auth/documents",
+	"docs.readonly":     "https://www.googleapis.com/auth/documents.readonly",
+	"slides":            "https://www.googleapis.com/auth/presentations",
+	"slides.readonly":   "https://www.googleapis.com/auth/presentations.readonly",
+	"gmail.readonly":    "https://www.googleapis.com/auth/gmail.readonly",
+	"gmail.send":        "https://www.googleapis.com/auth/gmail.send",
+	"gmail.modify":      "https://www.googleapis.com/auth/gmail.modify",
+	"calendar":          "https://www.googleapis.com/auth/calendar",
+	"calendar.readonly": "https://www.googleapis.com/auth/calendar.readonly",
+	"bigquery":          "https://www.googleapis.com/auth/bigquery",
+	"cloud-platform":    "https://www.googleapis.com/auth/cloud-platform",
+}
+
+// parseScopes splits list at commas, expanding the short names of
+// shortScopes. Scopes given in full, as URLs, are kept as they are.
+func parseScopes(list string) ([]string, error) {
+	var scopes []string
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			full, ok := shortScopes[s]
+			if !ok {
+				return nil, fmt.Errorf("googleauth: unknown scope %q; give its URL", s)
+			}
+			s = full
+		}
+		scopes = append(scopes, s)
+	}
+
+	return scopes, nil
+}
+
+func login(ctx context.Context, args []string) error {
+	a, err := authenticator(ctx, "login", args, true)
+	if err != nil {
+		return err
+	}
+	tok, err := a.Token(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("authorized, access token expires %s\n", tok.Expiry.Format(time.RFC3339))
+
+	return nil
+}
+
+func status(ctx context.Context, args []string) error {
+	a, err := authenticator(ctx, "status", args, false)
+	if err != nil {
+		return err
+	}
+	st, err := a.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if !st.Cached {
+		fmt.Println("no cached token")
+		return nil
+	}
+
+	account := st.Account
+	if account == "" {
+		account = "unknown"
+	}
+	fmt.Printf("account:    %s\n", account)
+	fmt.Printf("scopes:     %s\n", strings.Join(st.Scopes, " "))
+	fmt.Printf("expires in: %s\n", st.ExpiresIn.Round(time.Second))
+	if !st.GrantedAt.IsZero() {
+		fmt.Printf("granted:    %s\n", st.GrantedAt.Format(time.RFC3339))
+	}
+	if st.RefreshTokenAlive {
+		fmt.Println("refresh:    ok")
+	} else {
+		fmt.Printf("refresh:    failed: %v\n", st.RefreshErr)
+	}
+
+	return nil
+}
+
+func refresh(ctx context.Context, args []string) error {
+	a, err := authenticator(ctx, "refresh", args, false)
+	if err != nil {
+		return err
+	}
+	tok, err := a.RefreshNow(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("refreshed, access token expires %s\n", tok.Expiry.Format(time.RFC3339))
+
+	return nil
+}
+
+func revoke(ctx context.Context, args []string) error {
+	a, err := authenticator(ctx, "revoke", args, false)
+	if err != nil {
+		return err
+	}
+	if err := a.Revoke(ctx); err != nil {
+		return err
+	}
+	fmt.Println("revoked")
+
+	return nil
+}
+
+func rotateKey(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("rotate-key", flag.ExitOnError)
+	adminKey := fs.String("admin-key", "", "service account key to make the IAM calls with")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	var client *http.Client
+	if *adminKey != "" {
+		b, err := ioutil.ReadFile(*adminKey)
+		if err != nil {
+			return err
+		}
+		conf, err := google.JWTConfigFromJSON(b, "https://www.googleapis.com/auth/cloud-platform")
+		if err != nil {
+			return err
+		}
+		client = conf.Client(ctx)
+	}
+
+	id, err := googleauth.RotateKey(ctx, client, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s now holds key %s\n", fs.Arg(0), id)
+
+	return nil
+}
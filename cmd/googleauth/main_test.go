@@ -0,0 +1,32 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseScopes(t *testing.T) {
+	got, err := parseScopes("drive,sheets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"https://www.googleapis.com/auth/drive",
+		"https://www.googleapis.com/auth/spreadsheets",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseScopes(drive,sheets) = %q, want %q", got, want)
+	}
+
+	got, err = parseScopes(" openid, https://www.googleapis.com/auth/tasks ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"openid", "https://www.googleapis.com/auth/tasks"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseScopes = %q, want %q", got, want)
+	}
+
+	if _, err := parseScopes("drive,spreadsheet"); err == nil {
+		t.Fatal("parseScopes accepted an unknown short name")
+	}
+}
@@ -0,0 +1,122 @@
+// Command libgoogleauth builds the googleauth token cache and consent flows
+// as a C shared library, so programs written in other languages can share
+// tokens with Go programs using the package:
+//
+//	go build -buildmode=c-shared -o libgoogleauth.so ./cmd/libgoogleauth
+//
+// Every function takes the client secret file, the token file name and a
+// space-separated list of scopes. On failure it returns NULL (or -1) and
+// stores a message in *err. Strings returned through either path must be
+// released with googleauth_free.
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/jarodmeng/googleauth/v2"
+	"golang.org/x/oauth2"
+)
+
+type cToken struct {
+	AccessToken string    `json:"access_token"`
+	TokenType   string    `json:"token_type"`
+	Expiry      time.Time `json:"expiry"`
+}
+
+func newAuthenticator(secretFile, tokenFile, scopes *C.char) (*googleauth.Authenticator, error) {
+	return googleauth.NewAuthenticator(context.Background(),
+		googleauth.WithSecretFile(C.GoString(secretFile)),
+		googleauth.WithTokenFile(C.GoString(tokenFile)),
+		googleauth.WithScopes(strings.Fields(C.GoString(scopes))...))
+}
+
+func tokenResult(tok *oauth2.Token, err error, errOut **C.char) *C.char {
+	if err != nil {
+		setError(errOut, err)
+		return nil
+	}
+	b, err := json.Marshal(cToken{
+		AccessToken: tok.AccessToken,
+		TokenType:   tok.Type(),
+		Expiry:      tok.Expiry,
+	})
+	if err != nil {
+		setError(errOut, err)
+		return nil
+	}
+
+	return C.CString(string(b))
+}
+
+func setError(errOut **C.char, err error) {
+	if errOut != nil {
+		*errOut = C.CString(err.Error())
+	}
+}
+
+// googleauth_get_client_token returns the current access token as JSON
+// with access_token, token_type and expiry fields, running the consent
+// flow if no token is cached.
+//
+//export googleauth_get_client_token
+func googleauth_get_client_token(secretFile, tokenFile, scopes *C.char, errOut **C.char) *C.char {
+	a, err := newAuthenticator(secretFile, tokenFile, scopes)
+	if err != nil {
+		setError(errOut, err)
+		return nil
+	}
+
+	tok, err := a.Token(context.Background())
+
+	return tokenResult(tok, err, errOut)
+}
+
+// googleauth_refresh forces a refresh of the cached token and returns the
+// new access token like googleauth_get_client_token.
+//
+//export googleauth_refresh
+func googleauth_refresh(secretFile, tokenFile, scopes *C.char, errOut **C.char) *C.char {
+	a, err := newAuthenticator(secretFile, tokenFile, scopes)
+	if err != nil {
+		setError(errOut, err)
+		return nil
+	}
+	tok, err := a.RefreshNow(context.Background())
+
+	return tokenResult(tok, err, errOut)
+}
+
+// googleauth_revoke revokes the cached grant and deletes it. It returns 0
+// on success and -1 on failure.
+//
+//export googleauth_revoke
+func googleauth_revoke(secretFile, tokenFile, scopes *C.char, errOut **C.char) C.int {
+	a, err := newAuthenticator(secretFile, tokenFile, scopes)
+	if err == nil {
+		err = a.Revoke(context.Background())
+	}
+	if err != nil {
+		setError(errOut, err)
+		return -1
+	}
+
+	return 0
+}
+
+// googleauth_free releases a string returned by the library.
+//
+//export googleauth_free
+func googleauth_free(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
+
+func main() {}
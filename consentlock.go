@@ -0,0 +1,170 @@
+package googleauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	lockPoll = 500 * time.Millisecond
+	// lockWait bounds the wait for another process's consent flow unless
+	// WithFlowTimeout sets a bound.
+	lockWait = 10 * time.Minute
+)
+
+// errLockHeld is returned by tryLock when another process holds the lock.
+var errLockHeld = errors.New("googleauth: lock held by another process")
+
+// A fileLock is an exclusive lock on a file. The system releases it when
+// the holder exits, so a process that dies mid-flow leaves no stale lock.
+type fileLock struct {
+	f *os.File
+}
+
+// tryLock locks the file at path, creating it if needed, without waiting.
+// It fails with errLockHeld if another process holds the lock. The file
+// is never removed: a process could still lock it after the removal while
+// another locks its replacement.
+func tryLock(path string) (*fileLock, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := tryLockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	// The holder's process ID, for whoever wonders who holds it.
+	if f.Truncate(0) == nil {
+		fmt.Fprintln(f, os.Getpid())
+	}
+
+	return &fileLock{f: f}, nil
+}
+
+func (l *fileLock) unlock() {
+	unlockFile(l.f)
+	l.f.Close()
+}
+
+// consentLockPath returns the lock file guarding the consent flow for the
+// Authenticator's key, or "" if the store isn't shared between processes.
+func (a *Authenticator) consentLockPath() string {
+	var dir string
+	switch s := a.store.(type) {
+	case *MemoryStore:
+		return ""
+	case *FileStore:
+		dir = s.Dir
+	default:
+		var err error
+		dir, err = defaultCacheDir()
+		if err != nil {
+			return ""
+		}
+	}
+
+	return filepath.Join(dir, ".lock-"+url.QueryEscape(a.key))
+}
+
+var errLockWait = errors.New("googleauth: timed out waiting for another process to authorize")
+
+// flowLocks holds a semaphore per consent flow, so that goroutines and
+// Authenticators of one process sharing a token run a single flow between
+// them. The lock file cannot tell them apart.
+var flowLocks sync.Map // flowKey → chan struct{}
+
+// flowKey identifies the consent flow for the Authenticator's token
+// within the process.
+func (a *Authenticator) flowKey() string {
+	if path := a.consentLockPath(); path != "" {
+		return path
+	}
+
+	return fmt.Sprintf("%p/%s", a.store, a.key)
+}
+
+// lockConsent makes sure only one goroutine, and one process on the
+// machine, runs the consent flow for the key at a time. It either returns
+// a token that another one obtained meanwhile, or an unlock function to
+// call once this one is done with its own flow. Without a usable lock file
+// the flow runs after the other flows of the process.
+func (a *Authenticator) lockConsent(ctx context.Context) (*oauth2.Token, func(), error) {
+	v, _ := flowLocks.LoadOrStore(a.flowKey(), make(chan struct{}, 1))
+	sem := v.(chan struct{})
+	select {
+	case sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	release := func() { <-sem }
+	if tok, err := a.storeGet(); err == nil && a.usable(tok) {
+		release()
+		return tok, nil, nil
+	}
+
+	path := a.consentLockPath()
+	if path == "" || a.noPrompt {
+		return nil, release, nil
+	}
+	tok, unlock, err := a.lockConsentFile(ctx, path)
+	if tok != nil || err != nil {
+		release()
+		return tok, nil, err
+	}
+
+	return nil, func() {
+		unlock()
+		release()
+	}, nil
+}
+
+// lockConsentFile takes the lock file at path for the consent flow, as
+// lockConsent does for the process.
+func (a *Authenticator) lockConsentFile(ctx context.Context, path string) (*oauth2.Token, func(), error) {
+	wait := a.timeout
+	if wait <= 0 {
+		wait = lockWait
+	}
+	deadline := time.After(wait)
+	waiting := false
+	for {
+		l, err := tryLock(path)
+		if err == nil {
+			// The previous holder may have stored a token just before
+			// letting go.
+			if tok, err := a.storeGet(); err == nil && a.usable(tok) {
+				l.unlock()
+				return tok, nil, nil
+			}
+			return nil, l.unlock, nil
+		}
+		if err != errLockHeld {
+			a.logf("googleauth: cannot lock consent flow for %q: %v", a.key, err)
+			return nil, func() {}, nil
+		}
+
+		if !waiting {
+			a.logf("googleauth: another process is authorizing %q, waiting", a.key)
+			waiting = true
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-deadline:
+			return nil, nil, errLockWait
+		case <-time.After(lockPoll):
+		}
+		if tok, err := a.storeGet(); err == nil && a.usable(tok) {
+			return tok, nil, nil
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package googleauth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTryLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".lock-test")
+	l, err := tryLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tryLock(path); err != errLockHeld {
+		t.Fatalf("second tryLock = %v, want errLockHeld", err)
+	}
+	l.unlock()
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("lock file removed: %v", err)
+	}
+	l, err = tryLock(path)
+	if err != nil {
+		t.Fatalf("tryLock after unlock: %v", err)
+	}
+	l.unlock()
+}
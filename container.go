@@ -0,0 +1,78 @@
+package googleauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+const (
+	secretFileEnv = "GOOGLEAUTH_SECRET_FILE"
+	tokenFileEnv  = "GOOGLEAUTH_TOKEN_FILE"
+)
+
+var errNoOAuthClient = errors.New("googleauth: not supported with Application Default Credentials")
+
+// WithContainerDefaults configures the Authenticator for containers and
+// other unattended environments:
+//
+//   - Application Default Credentials, such as the metadata server or
+//     workload identity federation configured through
+//     GOOGLE_APPLICATION_CREDENTIALS, are used in preference to the OAuth
+//     client when available.
+//   - The client secret is read from $GOOGLEAUTH_SECRET_FILE unless given
+//     explicitly.
+//   - The consent flow never runs; without a usable cached token the
+//     Authenticator fails with ErrConsentRequired.
+//   - Tokens are written only to $GOOGLEAUTH_TOKEN_FILE or an explicitly
+//     configured store or cache directory, and otherwise kept in memory.
+func WithContainerDefaults() Option {
+	return func(s *settings) {
+		s.container = true
+	}
+}
+
+// applyContainerDefaults fills in what WithContainerDefaults promises once
+// all options have been applied, so explicit options win regardless of
+// their order.
+func (s *settings) applyContainerDefaults() {
+	s.noPrompt = true
+	if s.secret == nil && s.secretFile == "" {
+		s.secretFile = os.Getenv(secretFileEnv)
+	}
+
+	if s.store != nil || s.cacheDir != "" {
+		return
+	}
+	if file := os.Getenv(tokenFileEnv); file != "" {
+		s.cacheDir = filepath.Dir(file)
+		if s.tokenFile == "" {
+			s.tokenFile = filepath.Base(file)
+		}
+		return
+	}
+	s.store = &MemoryStore{}
+}
+
+// WithDefaultCredentials makes the Authenticator use Application Default
+// Credentials when they can be found: the key file named by
+// GOOGLE_APPLICATION_CREDENTIALS, the user credentials of gcloud auth
+// application-default login, or the metadata server on Compute Engine, GKE
+// and Cloud Run. Otherwise it falls back to the OAuth client and its
+// consent flow as usual, with the secret read from $GOOGLEAUTH_SECRET_FILE
+// unless given explicitly. The same program thus runs unchanged on a
+// laptop and on Google Cloud.
+func WithDefaultCredentials() Option {
+	return func(s *settings) {
+		s.adc = true
+	}
+}
+
+// FindDefaultClient creates an HTTP client from Application Default
+// Credentials, falling back to the consent flow for the client secret in
+// $GOOGLEAUTH_SECRET_FILE. See WithDefaultCredentials.
+func FindDefaultClient(ctx context.Context, scopes ...string) (*http.Client, error) {
+	return NewClient(ctx, nil, WithDefaultCredentials(), WithScopes(scopes...))
+}
@@ -0,0 +1,27 @@
+package googleauth
+
+import "sync"
+
+var defaults struct {
+	mu   sync.RWMutex
+	opts []Option
+}
+
+// SetDefaults replaces the package-wide default options. They are applied
+// before the options of every subsequent NewAuthenticator, CreateClient or
+// CreateClientFromFile call, which can still override them. Programs
+// typically call SetDefaults once at startup, but it is safe to call
+// concurrently with client creation.
+func SetDefaults(opts ...Option) {
+	defaults.mu.Lock()
+	defer defaults.mu.Unlock()
+
+	defaults.opts = append([]Option(nil), opts...)
+}
+
+func defaultOptions() []Option {
+	defaults.mu.RLock()
+	defer defaults.mu.RUnlock()
+
+	return defaults.opts
+}
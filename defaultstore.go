@@ -0,0 +1,7 @@
+//go:build !js
+
+package googleauth
+
+func newDefaultStore(cacheDir string) (TokenStore, error) {
+	return NewFileStore(cacheDir)
+}
@@ -0,0 +1,110 @@
+package googleauth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// contextAwareConfig is where the endpoint verification helper
+// (SecureConnect) records how to obtain the device certificate, relative
+// to the home directory.
+const contextAwareConfig = ".secureConnect/context_aware_config.json"
+
+// WithDeviceCertificate presents the endpoint verification device
+// certificate of a managed machine on every connection to Google APIs, so
+// requests satisfy BeyondCorp context-aware access policies. Requests to
+// *.googleapis.com are sent to the matching mTLS endpoints, as those
+// policies require. The certificate is obtained from the helper configured
+// in ~/.secureConnect/context_aware_config.json.
+func WithDeviceCertificate() Option {
+	return func(s *settings) {
+		s.deviceCert = true
+	}
+}
+
+// deviceCertSource runs the certificate provider command and caches its
+// result until the certificate expires.
+type deviceCertSource struct {
+	cmd []string
+
+	mu   sync.Mutex
+	cert *tls.Certificate
+}
+
+func newDeviceCertSource() (*deviceCertSource, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(filepath.Join(home, contextAwareConfig))
+	if err != nil {
+		return nil, fmt.Errorf("googleauth: no device certificate helper: %v", err)
+	}
+	var c struct {
+		Command []string `json:"cert_provider_command"`
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	if len(c.Command) == 0 {
+		return nil, errors.New("googleauth: empty cert_provider_command")
+	}
+
+	return &deviceCertSource{cmd: c.Command}, nil
+}
+
+func (s *deviceCertSource) get(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cert != nil && time.Now().Before(s.cert.Leaf.NotAfter) {
+		return s.cert, nil
+	}
+
+	// The helper prints the certificate chain and private key as PEM.
+	out, err := exec.Command(s.cmd[0], s.cmd[1:]...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("googleauth: device certificate helper: %v", err)
+	}
+	cert, err := tls.X509KeyPair(out, out)
+	if err != nil {
+		return nil, err
+	}
+	cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	s.cert = &cert
+
+	return s.cert, nil
+}
+
+// mtlsTransport sends requests for Google APIs to their mTLS endpoints,
+// foo.googleapis.com becoming foo.mtls.googleapis.com.
+type mtlsTransport struct {
+	base http.RoundTripper
+}
+
+func (t *mtlsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Hostname()
+	if !strings.HasSuffix(host, ".googleapis.com") || strings.HasSuffix(host, ".mtls.googleapis.com") {
+		return t.base.RoundTrip(req)
+	}
+
+	r := req.Clone(req.Context())
+	r.URL.Host = strings.Replace(req.URL.Host, ".googleapis.com", ".mtls.googleapis.com", 1)
+	r.Host = ""
+
+	return t.base.RoundTrip(r)
+}
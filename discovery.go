@@ -0,0 +1,187 @@
+package googleauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// providerMetadata is the part of an OpenID Connect discovery document
+// googleauth uses.
+type providerMetadata struct {
+	Issuer        string `json:"issuer"`
+	AuthURL       string `json:"authorization_endpoint"`
+	TokenURL      string `json:"token_endpoint"`
+	DeviceAuthURL string `json:"device_authorization_endpoint"`
+	JWKSURL       string `json:"jwks_uri"`
+	RevokeURL     string `json:"revocation_endpoint"`
+}
+
+var (
+	discoveryMu sync.Mutex
+	discovered  = map[string]*discovery{}
+	keySets     = map[string]*keySet{}
+)
+
+// A discovery is the discovery document of an issuer, being fetched or
+// fetched successfully.
+type discovery struct {
+	done chan struct{} // closed when the fetch ends
+	m    *providerMetadata
+	err  error
+}
+
+// discover fetches and caches the discovery document of issuer. The fetch
+// runs outside discoveryMu, so a slow issuer only holds up its own
+// callers, who wait for the fetch in progress rather than start their own.
+// Failures are not cached.
+func discover(ctx context.Context, issuer string) (*providerMetadata, error) {
+	for {
+		discoveryMu.Lock()
+		d, ok := discovered[issuer]
+		if !ok {
+			d = &discovery{done: make(chan struct{})}
+			discovered[issuer] = d
+			discoveryMu.Unlock()
+
+			d.m, d.err = fetchDiscovery(ctx, issuer)
+			if d.err != nil {
+				discoveryMu.Lock()
+				delete(discovered, issuer)
+				discoveryMu.Unlock()
+			}
+			close(d.done)
+			return d.m, d.err
+		}
+		discoveryMu.Unlock()
+
+		select {
+		case <-d.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if d.err == nil {
+			return d.m, nil
+		}
+		// The fetch failed, perhaps only for its caller's context; try
+		// again.
+	}
+}
+
+func fetchDiscovery(ctx context.Context, issuer string) (*providerMetadata, error) {
+	u := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := contextClient(ctx).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("googleauth: fetching %s: %s", u, resp.Status)
+	}
+	m := &providerMetadata{}
+	if err := json.NewDecoder(resp.Body).Decode(m); err != nil {
+		return nil, err
+	}
+	// The document must be the issuer's own, or it could vouch for
+	// tokens of another.
+	if m.Issuer != issuer {
+		return nil, fmt.Errorf("googleauth: discovery document of %s is for issuer %q", issuer, m.Issuer)
+	}
+
+	return m, nil
+}
+
+// jwks returns the shared key set at url.
+func jwks(url string) *keySet {
+	discoveryMu.Lock()
+	defer discoveryMu.Unlock()
+
+	if url == googleCertsURL {
+		return googleKeys
+	}
+	k, ok := keySets[url]
+	if !ok {
+		k = &keySet{url: url}
+		keySets[url] = k
+	}
+
+	return k
+}
+
+// WithIssuer points the consent flows and refreshes at the OpenID Connect
+// provider issuer, such as a Google Identity Platform tenant, instead of
+// Google accounts, taking the endpoints from its discovery document. The
+// client secret's own endpoints are ignored. Revoke uses the provider's
+// revocation endpoint and fails if it publishes none; VerifyToken asks
+// Google, so it only works with Google's issuer.
+func WithIssuer(issuer string) Option {
+	return func(s *settings) {
+		s.issuer = issuer
+	}
+}
+
+// googleIssuer is the issuer of Google accounts.
+const googleIssuer = "https://accounts.google.com"
+
+// useIssuer replaces the client's endpoints with those of issuer.
+func (a *Authenticator) useIssuer(ctx context.Context, issuer string) error {
+	m, err := discover(ctx, issuer)
+	if err != nil {
+		return err
+	}
+	a.config.Endpoint.AuthURL = m.AuthURL
+	a.config.Endpoint.TokenURL = m.TokenURL
+	a.config.Endpoint.DeviceAuthURL = m.DeviceAuthURL
+	a.revokeURL = m.RevokeURL
+
+	return nil
+}
+
+// An IDTokenVerifier verifies ID tokens issued by Google or by any
+// OpenID Connect provider, whose keys it finds through discovery.
+type IDTokenVerifier struct {
+	// Issuer is the expected iss claim, such as
+	// https://securetoken.google.com/my-project for Identity Platform.
+	// Empty means Google accounts.
+	Issuer string
+	// Audience is the expected aud claim, usually the client ID.
+	Audience string
+}
+
+// Verify checks the ID token raw and returns its claims.
+func (v *IDTokenVerifier) Verify(ctx context.Context, raw string) (*IDTokenClaims, error) {
+	keys := googleKeys
+	if v.Issuer != "" {
+		m, err := discover(ctx, v.Issuer)
+		if err != nil {
+			return nil, err
+		}
+		keys = jwks(m.JWKSURL)
+	}
+
+	c, err := verifyJWT(ctx, keys, raw)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case v.Issuer == "" && (c.Issuer == "accounts.google.com" || c.Issuer == "https://accounts.google.com"):
+	case v.Issuer != "" && c.Issuer == v.Issuer:
+	default:
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIDToken, c.Issuer)
+	}
+	for _, aud := range audiences(c.Claims) {
+		if aud == v.Audience {
+			c.Audience = aud
+			return c, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: unexpected audience %q", ErrInvalidIDToken, c.Audience)
+}
@@ -0,0 +1,110 @@
+package googleauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const testSecret = `{"installed":{"client_id":"id","client_secret":"secret","redirect_uris":["http://localhost"]}}`
+
+// newIssuer serves a discovery document, with a revocation endpoint if
+// revoke is set, and counts the revocations.
+func newIssuer(t *testing.T, revoke bool, revoked *int) *httptest.Server {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			m := map[string]string{
+				"issuer":                 srv.URL,
+				"authorization_endpoint": srv.URL + "/auth",
+				"token_endpoint":         srv.URL + "/token",
+			}
+			if revoke {
+				m["revocation_endpoint"] = srv.URL + "/revoke"
+			}
+			json.NewEncoder(w).Encode(m)
+		case "/revoke":
+			*revoked++
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestIssuerRevocationEndpoint(t *testing.T) {
+	ctx := context.Background()
+	for _, revoke := range []bool{true, false} {
+		var revoked int
+		srv := newIssuer(t, revoke, &revoked)
+		store := &MemoryStore{}
+		a, err := NewAuthenticator(ctx, WithSecret([]byte(testSecret)), WithIssuer(srv.URL), WithTokenStore(store))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Put(a.key, &oauth2.Token{AccessToken: "at", RefreshToken: "rt"}); err != nil {
+			t.Fatal(err)
+		}
+
+		err = a.Revoke(ctx)
+		switch {
+		case revoke && (err != nil || revoked != 2):
+			t.Errorf("Revoke = %v after %d revocations, want nil after 2", err, revoked)
+		case !revoke && (err == nil || !strings.Contains(err.Error(), "revocation endpoint")):
+			t.Errorf("Revoke without an endpoint = %v", err)
+		}
+	}
+}
+
+func TestVerifyTokenOtherIssuer(t *testing.T) {
+	ctx := context.Background()
+	srv := newIssuer(t, true, new(int))
+	a, err := NewAuthenticator(ctx, WithSecret([]byte(testSecret)), WithIssuer(srv.URL), WithoutCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.setToken(&oauth2.Token{AccessToken: "at"})
+	if _, err := a.VerifyToken(ctx); err == nil {
+		t.Fatal("VerifyToken succeeded for a non-Google issuer")
+	}
+}
+
+func TestDiscoverSlowIssuer(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		http.NotFound(w, r)
+	}))
+	defer slow.Close()
+	defer close(release)
+	fast := newIssuer(t, true, new(int))
+
+	go discover(context.Background(), slow.URL)
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	m, err := discover(ctx, fast.URL)
+	if err != nil {
+		t.Fatalf("discovery of another issuer: %v", err)
+	}
+	if m.TokenURL != fast.URL+"/token" {
+		t.Fatalf("token endpoint %q", m.TokenURL)
+	}
+
+	wctx, wcancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer wcancel()
+	if _, err := discover(wctx, slow.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("waiting for the slow issuer = %v, want the context's deadline", err)
+	}
+}
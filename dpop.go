@@ -0,0 +1,283 @@
+package googleauth
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A DPoPKey is the key pair tokens are bound to under DPoP (RFC 9449).
+// Keep it next to the token cache: a DPoP-bound refresh token only works
+// with the key it was issued for.
+type DPoPKey struct {
+	priv *ecdsa.PrivateKey
+}
+
+// NewDPoPKey generates a P-256 key pair, for the ES256 algorithm.
+func NewDPoPKey() (*DPoPKey, error) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DPoPKey{priv: priv}, nil
+}
+
+// ParseDPoPKey parses a key encoded by PEM.
+func ParseDPoPKey(b []byte) (*DPoPKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("googleauth: no PEM data in DPoP key")
+	}
+	priv, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if priv.Curve != elliptic.P256() {
+		return nil, errors.New("googleauth: DPoP key must use P-256")
+	}
+
+	return &DPoPKey{priv: priv}, nil
+}
+
+// PEM encodes the private key for storage.
+func (k *DPoPKey) PEM() ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(k.priv)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+func (k *DPoPKey) jwk() map[string]string {
+	b64 := base64.RawURLEncoding.EncodeToString
+	return map[string]string{
+		"kty": "EC",
+		"crv": "P-256",
+		"x":   b64(k.priv.X.FillBytes(make([]byte, 32))),
+		"y":   b64(k.priv.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+// Thumbprint returns the RFC 7638 JWK thumbprint of the public key, the
+// value of the dpop_jkt authorization parameter.
+func (k *DPoPKey) Thumbprint() string {
+	jwk := k.jwk()
+	// Members in lexicographic order, no whitespace.
+	canon := `{"crv":"P-256","kty":"EC","x":"` + jwk["x"] + `","y":"` + jwk["y"] + `"}`
+	sum := sha256.Sum256([]byte(canon))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// proof returns a DPoP proof JWT for a request. accessToken is empty for
+// requests to the token endpoint.
+func (k *DPoPKey) proof(method, target, accessToken, nonce string) (string, error) {
+	header := map[string]interface{}{
+		"typ": "dpop+jwt",
+		"alg": "ES256",
+		"jwk": k.jwk(),
+	}
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+	claims := map[string]interface{}{
+		"jti": base64.RawURLEncoding.EncodeToString(jti),
+		"htm": method,
+		"htu": target,
+		"iat": time.Now().Unix(),
+	}
+	if accessToken != "" {
+		sum := sha256.Sum256([]byte(accessToken))
+		claims["ath"] = base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signing := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	sum := sha256.Sum256([]byte(signing))
+	r, s, err := ecdsa.Sign(rand.Reader, k.priv, sum[:])
+	if err != nil {
+		return "", err
+	}
+	sig := append(fixed32(r), fixed32(s)...)
+
+	return signing + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func fixed32(n *big.Int) []byte {
+	return n.FillBytes(make([]byte, 32))
+}
+
+// WithDPoP binds tokens to key with DPoP proofs added to token requests
+// and, for DPoP-bound access tokens, to API requests. A nil key generates
+// a fresh one, which only suits tokens that are not cached across runs.
+// Google endpoints don't require DPoP yet; this prepares deployments for
+// sender-constrained tokens.
+func WithDPoP(key *DPoPKey) Option {
+	return func(s *settings) {
+		if key == nil {
+			var err error
+			key, err = NewDPoPKey()
+			if err != nil {
+				if s.err == nil {
+					s.err = err
+				}
+				return
+			}
+		}
+		s.dpop = key
+	}
+}
+
+// dpopTransport adds a DPoP proof to requests for the token endpoint and
+// to requests authorized with a DPoP-bound access token, and retries those
+// once when the server asks for a nonce. Other requests pass through
+// untouched.
+type dpopTransport struct {
+	key  *DPoPKey
+	base http.RoundTripper
+
+	mu       sync.Mutex
+	tokenURL string            // without query, set once the client is known
+	nonces   map[string]string // by host
+}
+
+// setTokenURL names the token endpoint, whose requests carry proofs.
+func (t *dpopTransport) setTokenURL(tokenURL string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tokenURL = tokenURL
+}
+
+func (t *dpopTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var accessToken string
+	if auth := req.Header.Get("Authorization"); strings.HasPrefix(auth, "DPoP ") {
+		accessToken = strings.TrimPrefix(auth, "DPoP ")
+	} else if !t.isTokenEndpoint(req.URL) {
+		return t.base.RoundTrip(req)
+	}
+
+	// The retry sends the body again. Bodies net/http can recreate are left
+	// alone; only others are read into memory.
+	getBody := req.GetBody
+	if req.Body != nil && req.Body != http.NoBody && getBody == nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		getBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		req = req.Clone(req.Context())
+		req.Body, _ = getBody()
+	}
+
+	resp, err := t.send(req, accessToken)
+	if err != nil || !t.wantsNonce(resp) {
+		return resp, err
+	}
+	resp.Body.Close()
+	r := req.Clone(req.Context())
+	if getBody != nil {
+		if r.Body, err = getBody(); err != nil {
+			return nil, err
+		}
+	}
+
+	return t.send(r, accessToken)
+}
+
+func (t *dpopTransport) isTokenEndpoint(u *url.URL) bool {
+	t.mu.Lock()
+	tokenURL := t.tokenURL
+	t.mu.Unlock()
+
+	// Under WithDeviceCertificate the request is already bound for the
+	// mTLS endpoint.
+	target := strings.Replace(withoutQuery(u), ".mtls.googleapis.com", ".googleapis.com", 1)
+
+	return tokenURL != "" && target == tokenURL
+}
+
+func withoutQuery(u *url.URL) string {
+	v := *u
+	v.RawQuery, v.Fragment = "", ""
+
+	return v.String()
+}
+
+func (t *dpopTransport) send(req *http.Request, accessToken string) (*http.Response, error) {
+	host := req.URL.Host
+	t.mu.Lock()
+	nonce := t.nonces[host]
+	t.mu.Unlock()
+	proof, err := t.key.proof(req.Method, withoutQuery(req.URL), accessToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	r := req.Clone(req.Context())
+	r.Header.Set("DPoP", proof)
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	if n := resp.Header.Get("DPoP-Nonce"); n != "" {
+		t.mu.Lock()
+		if t.nonces == nil {
+			t.nonces = make(map[string]string)
+		}
+		t.nonces[host] = n
+		t.mu.Unlock()
+	}
+
+	return resp, nil
+}
+
+// wantsNonce reports whether resp rejected the proof for lacking the
+// nonce it now supplies.
+func (t *dpopTransport) wantsNonce(resp *http.Response) bool {
+	if resp.Header.Get("DPoP-Nonce") == "" {
+		return false
+	}
+	switch resp.StatusCode {
+	case http.StatusBadRequest, http.StatusUnauthorized:
+	default:
+		return false
+	}
+	if strings.Contains(resp.Header.Get("WWW-Authenticate"), "use_dpop_nonce") {
+		return true
+	}
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+
+	return err == nil && bytes.Contains(b, []byte("use_dpop_nonce"))
+}
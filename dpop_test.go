@@ -0,0 +1,65 @@
+package googleauth
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDPoPTransport(t *testing.T) {
+	var proofs, bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		proofs = append(proofs, r.Header.Get("DPoP"))
+		bodies = append(bodies, string(b))
+		if r.URL.Path == "/token" && len(proofs) == 1 {
+			w.Header().Set("DPoP-Nonce", "n1")
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error":"use_dpop_nonce"}`)
+		}
+	}))
+	defer srv.Close()
+	key, err := NewDPoPKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dt := &dpopTransport{key: key, base: http.DefaultTransport}
+	dt.setTokenURL(srv.URL + "/token")
+	c := &http.Client{Transport: dt}
+
+	// A body net/http cannot recreate, to be buffered for the retry.
+	req, _ := http.NewRequest("POST", srv.URL+"/token", ioutil.NopCloser(strings.NewReader("grant_type=x")))
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(proofs) != 2 || proofs[1] == "" || bodies[1] != "grant_type=x" {
+		t.Fatalf("token request: status %d, proofs %q, bodies %q", resp.StatusCode, proofs, bodies)
+	}
+	if dt.nonces[req.URL.Host] != "n1" {
+		t.Fatalf("nonces = %v", dt.nonces)
+	}
+
+	req, _ = http.NewRequest("GET", srv.URL+"/api", nil)
+	req.Header.Set("Authorization", "Bearer at")
+	if resp, err = c.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if p := proofs[len(proofs)-1]; p != "" {
+		t.Fatalf("bearer request got proof %q", p)
+	}
+
+	req.Header.Set("Authorization", "DPoP at")
+	if resp, err = c.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if p := proofs[len(proofs)-1]; p == "" {
+		t.Fatal("DPoP request got no proof")
+	}
+}
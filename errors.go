@@ -0,0 +1,125 @@
+package googleauth
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/oauth2"
+)
+
+var (
+	// ErrTokenNotCached is returned by a TokenStore when it holds no token
+	// for the requested key.
+	ErrTokenNotCached = errors.New("googleauth: token not cached")
+
+	// ErrInvalidSecret is returned when the client secret is missing or
+	// cannot be parsed.
+	ErrInvalidSecret = errors.New("googleauth: invalid client secret")
+
+	// ErrInsecureSecretFile is returned under WithStrictSecretPermissions
+	// when the client secret file can be read by other users.
+	ErrInsecureSecretFile = errors.New("googleauth: insecure client secret file")
+
+	// ErrConsentRequired is returned when no usable token is cached and
+	// the Authenticator may not run an interactive consent flow.
+	ErrConsentRequired = errors.New("googleauth: interactive consent required")
+
+	// ErrUnsupportedClient is returned when the OAuth client type allows
+	// none of the consent flows available to the program.
+	ErrUnsupportedClient = errors.New("googleauth: unsupported client type")
+
+	// ErrReauthorizationRequired is returned when Google rejects the
+	// cached refresh token because it was revoked, expired or has been
+	// unused for too long. The dead token is removed from the store; the
+	// user has to authorize the application again, which the next
+	// Authenticator.Token or CreateClient call will do.
+	ErrReauthorizationRequired = errors.New("googleauth: refresh token revoked or expired, reauthorization required")
+
+	// ErrInvalidIDToken is returned when an ID token fails verification.
+	ErrInvalidIDToken = errors.New("googleauth: invalid ID token")
+
+	// ErrNotListable is returned when the keys of a TokenStore are needed
+	// but it cannot enumerate them.
+	ErrNotListable = errors.New("googleauth: token store cannot list its keys")
+
+	// ErrInvalidToken is returned when a nil or unusable token is passed
+	// to a TokenStore, and by VerifyToken when Google rejects the token.
+	ErrInvalidToken = errors.New("googleauth: invalid token")
+
+	// ErrCacheKey is returned when an encrypted token cache cannot be
+	// read because no key or the wrong key was given, or when the key is
+	// unusable.
+	ErrCacheKey = errors.New("googleauth: wrong or missing token cache key")
+
+	// ErrInternal is returned when googleauth or a component plugged into
+	// it panics. The error never carries the panic value.
+	ErrInternal = errors.New("googleauth: internal error")
+)
+
+// ErrTokenExpired is returned when the access token has expired and there
+// is no refresh token to renew it with. It also matches ErrConsentRequired.
+var ErrTokenExpired error = tokenExpiredError{}
+
+type tokenExpiredError struct{}
+
+func (tokenExpiredError) Error() string {
+	return "googleauth: access token expired and cannot be refreshed"
+}
+
+func (tokenExpiredError) Is(target error) bool {
+	return target == ErrConsentRequired
+}
+
+// An ExchangeError is returned when the token endpoint rejects a request:
+// the exchange of an authorization or device code, or a refresh. A
+// refresh rejected with invalid_grant also matches
+// ErrReauthorizationRequired.
+type ExchangeError struct {
+	// Op is "exchange", "device" or "refresh".
+	Op string
+	// Code is the OAuth error code, such as invalid_grant, or empty if the
+	// endpoint answered with no error code.
+	Code string
+	// Description is the human-readable error_description, if any.
+	Description string
+	// StatusCode is the HTTP status of the response.
+	StatusCode int
+	// Err is the underlying *oauth2.RetrieveError.
+	Err error
+}
+
+func (e *ExchangeError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("googleauth: %s failed: %v", e.Op, e.Err)
+	}
+	if e.Description == "" {
+		return fmt.Sprintf("googleauth: %s failed: %s", e.Op, e.Code)
+	}
+
+	return fmt.Sprintf("googleauth: %s failed: %s: %s", e.Op, e.Code, e.Description)
+}
+
+func (e *ExchangeError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether a refresh rejected with invalid_grant is compared
+// with ErrReauthorizationRequired.
+func (e *ExchangeError) Is(target error) bool {
+	return target == ErrReauthorizationRequired && e.Op == "refresh" && e.Code == "invalid_grant"
+}
+
+// exchangeError turns an *oauth2.RetrieveError from op into an
+// *ExchangeError, and returns other errors as they are.
+func exchangeError(op string, err error) error {
+	var re *oauth2.RetrieveError
+	if !errors.As(err, &re) {
+		return err
+	}
+	e := &ExchangeError{Op: op, Code: re.ErrorCode, Description: re.ErrorDescription, Err: re}
+	if re.Response != nil {
+		e.StatusCode = re.Response.StatusCode
+	}
+
+	return e
+}
@@ -0,0 +1,116 @@
+package googleauth
+
+import (
+	"fmt"
+	"time"
+)
+
+// An EventKind identifies a stage of the consent flow.
+type EventKind int
+
+// Stages of the consent flow, in the order they are reached.
+const (
+	// FlowStarted is sent when no usable token is cached and the
+	// consent flow begins.
+	FlowStarted EventKind = iota
+	// BrowserOpened is sent when the consent page was opened in the
+	// browser.
+	BrowserOpened
+	// WaitingForConsent is sent when the user has been asked to approve,
+	// in the browser or on another device.
+	WaitingForConsent
+	// CodeReceived is sent when an authorization code flow received the
+	// code and is about to exchange it.
+	CodeReceived
+	// TokenSaved is sent when the new token has been stored.
+	TokenSaved
+	// Failed is sent when the flow fails; Event.Stage and Event.Err tell
+	// where and why.
+	Failed
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case FlowStarted:
+		return "FlowStarted"
+	case BrowserOpened:
+		return "BrowserOpened"
+	case WaitingForConsent:
+		return "WaitingForConsent"
+	case CodeReceived:
+		return "CodeReceived"
+	case TokenSaved:
+		return "TokenSaved"
+	case Failed:
+		return "Failed"
+	}
+
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
+
+// An Event reports progress of the consent flow.
+type Event struct {
+	Kind EventKind
+	Time time.Time
+	// Stage is the last stage reached before a Failed event.
+	Stage EventKind
+	// Err is the cause of a Failed event.
+	Err error
+}
+
+// WithEventHandler calls h with an Event at each stage of the consent
+// flow, so applications can render their own progress and log where a
+// flow failed. h is called from the goroutine running the flow and must
+// not block; to receive events on a channel, send from h without
+// blocking.
+func WithEventHandler(h func(Event)) Option {
+	return func(s *settings) {
+		s.events = h
+	}
+}
+
+// emit reports that the flow reached stage kind.
+func (a *Authenticator) emit(kind EventKind) {
+	a.eventMu.Lock()
+	a.stage = kind
+	a.eventMu.Unlock()
+	a.logf("googleauth: consent flow for %q: %v", a.key, kind)
+	if a.events != nil {
+		a.events(Event{Kind: kind, Time: time.Now()})
+	}
+}
+
+// emitFailed reports that the flow failed with err.
+func (a *Authenticator) emitFailed(err error) {
+	a.eventMu.Lock()
+	stage := a.stage
+	a.eventMu.Unlock()
+	a.logf("googleauth: consent flow for %q failed after %v: %v", a.key, stage, err)
+	if a.events != nil {
+		a.events(Event{Kind: Failed, Time: time.Now(), Stage: stage, Err: err})
+	}
+}
+
+// consentShown reports that the consent page was presented.
+func (a *Authenticator) consentShown(opened bool) {
+	if opened {
+		a.emit(BrowserOpened)
+	}
+	a.emit(WaitingForConsent)
+}
+
+// eventPrompter sends events for what the flows show through a Prompter.
+type eventPrompter struct {
+	Prompter
+	a *Authenticator
+}
+
+func (p eventPrompter) ShowConsentURL(authURL string, opened bool) {
+	p.a.consentShown(opened)
+	p.Prompter.ShowConsentURL(authURL, opened)
+}
+
+func (p eventPrompter) ShowDeviceCode(verificationURL, userCode string, expiry time.Time) {
+	p.a.emit(WaitingForConsent)
+	p.Prompter.ShowDeviceCode(verificationURL, userCode, expiry)
+}
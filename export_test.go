@@ -0,0 +1,12 @@
+package googleauth
+
+import "time"
+
+// SetTimeNow replaces the clock grant ages are measured by until the
+// returned function restores it.
+func SetTimeNow(now func() time.Time) (restore func()) {
+	old := timeNow
+	timeNow = now
+
+	return func() { timeNow = old }
+}
@@ -0,0 +1,40 @@
+package googleauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"golang.org/x/oauth2/google"
+)
+
+// isExternalAccount reports whether secret is an external_account
+// credential configuration for workload identity federation rather than an
+// OAuth client secret.
+func isExternalAccount(secret []byte) bool {
+	var j struct {
+		Type string `json:"type"`
+	}
+
+	return json.Unmarshal(secret, &j) == nil && j.Type == "external_account"
+}
+
+// externalAccountCredentials loads the workload identity federation
+// configuration config, as written by gcloud iam workload-identity-pools
+// create-cred-config for AWS, Azure, GitHub Actions and other OIDC or SAML
+// providers. Each token is obtained by exchanging the workload's own
+// credential with the Security Token Service, then impersonating the
+// configuration's service account if it names one. No user is involved and
+// nothing is cached. The Security Token Service requires a scope, so
+// cloud-platform is requested when none is given.
+func externalAccountCredentials(ctx context.Context, config []byte, scopes []string) (*google.Credentials, error) {
+	if len(scopes) == 0 {
+		scopes = []string{cloudPlatformScope}
+	}
+	creds, err := google.CredentialsFromJSONWithParams(ctx, config, google.CredentialsParams{Scopes: scopes})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSecret, err)
+	}
+
+	return creds, nil
+}
@@ -0,0 +1,23 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+
+package googleauth
+
+import (
+	"errors"
+	"os"
+)
+
+// lockFile does nothing here; FileStore relies on atomic renames alone.
+func lockFile(f *os.File, exclusive bool) error {
+	return nil
+}
+
+// tryLockFile fails: without file locks the consent flow is only
+// serialized within the process.
+func tryLockFile(f *os.File) error {
+	return errors.New("googleauth: file locks not supported")
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,32 @@
+package googleauth
+
+import "crypto/tls"
+
+// WithFIPSMode restricts the cryptography used by the package to FIPS 140
+// approved algorithms. Connections made for the token exchange, refreshes,
+// revocation and by the returned Client negotiate TLS 1.2 with approved
+// cipher suites and curves only, and features that derive keys or encrypt
+// data pick approved primitives. A validated deployment still needs a
+// validated Go cryptographic module, such as one built with GOFIPS140 or
+// a boringcrypto toolchain.
+func WithFIPSMode() Option {
+	return func(s *settings) {
+		s.fips = true
+	}
+}
+
+func fipsTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// TLS 1.3 cipher suites aren't configurable and include
+		// ChaCha20-Poly1305, which is not approved.
+		MaxVersion: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+		},
+		CurvePreferences: []tls.CurveID{tls.CurveP256, tls.CurveP384},
+	}
+}
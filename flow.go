@@ -0,0 +1,160 @@
+package googleauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"strconv"
+
+	"golang.org/x/oauth2"
+)
+
+// A Flow selects how the user grants consent when no cached token exists.
+type Flow int
+
+const (
+	// FlowAuto picks the best flow the client and environment support:
+	// the loopback flow when a local browser is available, then the device
+	// flow, then pasting the code by hand.
+	FlowAuto Flow = iota
+	// FlowLoopback receives the code on a local HTTP server. It needs a
+	// "Desktop app" client.
+	FlowLoopback
+	// FlowDevice uses the device authorization grant: the user enters a
+	// short code on another device. It needs a "TVs and Limited Input
+	// devices" client.
+	FlowDevice
+	// FlowPaste has the user copy the code, or the address of the page the
+	// browser was redirected to, back into the terminal.
+	FlowPaste
+)
+
+func (f Flow) String() string {
+	switch f {
+	case FlowAuto:
+		return "auto"
+	case FlowLoopback:
+		return "loopback"
+	case FlowDevice:
+		return "device"
+	case FlowPaste:
+		return "paste"
+	}
+
+	return fmt.Sprintf("Flow(%d)", int(f))
+}
+
+// errDeviceUnsupported marks a device authorization request the client or
+// scopes don't allow, so FlowAuto can move on to the next flow.
+var errDeviceUnsupported = errors.New("googleauth: device flow not supported for this client")
+
+func (a *Authenticator) runFlow(ctx context.Context) (*oauth2.Token, error) {
+	a.logf("googleauth: consent flow %v", a.flow)
+	if a.client.web {
+		return nil, fmt.Errorf("%w: the secret belongs to a web application "+
+			"client; create a \"Desktop app\" client for the loopback and "+
+			"paste flows or a \"TVs and Limited Input devices\" client for "+
+			"the device flow", ErrUnsupportedClient)
+	}
+	switch a.flow {
+	case FlowLoopback:
+		return a.authorizeCode(ctx, a.loopbackReceiver(), "")
+	case FlowDevice:
+		return a.deviceToken(ctx)
+	case FlowPaste:
+		return a.authorizeCode(ctx, a.pasteReceiver(), a.client.pasteRedirect())
+	}
+
+	if a.client.loopbackRedirect() != "" && hasLocalBrowser() && canListenLoopback(a.port) {
+		return a.authorizeCode(ctx, a.loopbackReceiver(), "")
+	}
+	tok, err := a.deviceToken(ctx)
+	if !errors.Is(err, errDeviceUnsupported) {
+		return tok, err
+	}
+	a.logf("%v", err)
+	if redirect := a.client.pasteRedirect(); redirect != "" {
+		return a.authorizeCode(ctx, a.pasteReceiver(), redirect)
+	}
+
+	return nil, fmt.Errorf("%w: the client has no loopback redirect URI and "+
+		"does not support the device flow; the out-of-band copy-paste flow "+
+		"is no longer available to new clients", ErrUnsupportedClient)
+}
+
+func (a *Authenticator) loopbackReceiver() *LoopbackReceiver {
+	return &LoopbackReceiver{Port: a.port, Prompter: a.prompter, Messages: a.msgs, noBrowser: a.noBrowser}
+}
+
+// pasteReceiver returns the receiver of the paste flow. A TerminalPrompter
+// leaves the instructions to it, as they differ from the other flows';
+// any other Prompter is told instead, and reports the events itself.
+func (a *Authenticator) pasteReceiver() *PasteReceiver {
+	p := a.prompter
+	if ep, ok := p.(eventPrompter); ok {
+		p = ep.Prompter
+	}
+	if tp, ok := p.(*TerminalPrompter); ok {
+		return &PasteReceiver{Out: tp.Out, Messages: a.msgs, shown: a.consentShown, noBrowser: a.noBrowser}
+	}
+
+	return &PasteReceiver{Prompter: a.prompter, Messages: a.msgs, noBrowser: a.noBrowser}
+}
+
+func (a *Authenticator) deviceToken(ctx context.Context) (*oauth2.Token, error) {
+	da, err := a.config.DeviceAuth(ctx)
+	if err != nil {
+		var rerr *oauth2.RetrieveError
+		if errors.As(err, &rerr) {
+			switch rerr.ErrorCode {
+			case "invalid_client", "unauthorized_client", "invalid_scope":
+				return nil, fmt.Errorf("%w: %v", errDeviceUnsupported, err)
+			}
+		}
+		return nil, err
+	}
+
+	uri := da.VerificationURIComplete
+	if uri == "" {
+		uri = da.VerificationURI
+	}
+	a.prompter.ShowDeviceCode(uri, da.UserCode, da.Expiry)
+	a.prompter.ShowProgress(a.msgs.WaitingForDevice)
+
+	tok, err := a.config.DeviceAccessToken(ctx, da, a.exchOpts...)
+	if err == nil {
+		a.logf("googleauth: device code approved for %q", a.key)
+	}
+
+	return tok, exchangeError("device", err)
+}
+
+// hasLocalBrowser reports whether a browser can probably be opened on this
+// machine, as opposed to a session over SSH or without a display.
+func hasLocalBrowser() bool {
+	if os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_TTY") != "" {
+		return false
+	}
+	switch runtime.GOOS {
+	case "windows", "darwin":
+		return true
+	}
+	if onCrostini() {
+		return true
+	}
+
+	return os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != ""
+}
+
+func canListenLoopback(port int) bool {
+	ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return false
+	}
+	ln.Close()
+
+	return true
+}
@@ -0,0 +1,540 @@
+// Package googleauthtest provides a fake Google OAuth server for testing
+// programs that use googleauth, without real credentials or a browser.
+//
+// For example:
+//
+//	srv := googleauthtest.NewServer()
+//	defer srv.Close()
+//	client, err := googleauth.NewClient(ctx, srv.Secret(), srv.Options()...)
+//
+// The consent flow is approved at once, and the token is cached in memory,
+// so the whole path from consent through caching to refresh runs in tests.
+// The device flow is approved at its first poll. Grants with the openid
+// scope come with an ID token that googleauth verifies as Google's.
+package googleauthtest
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jarodmeng/googleauth/v2"
+)
+
+const (
+	// ClientID is the client ID of the secret returned by Secret.
+	ClientID = "googleauthtest.apps.googleusercontent.com"
+	// ClientSecret is the client secret of the secret returned by Secret.
+	ClientSecret = "googleauthtest-secret"
+
+	// googleHost is where googleauth revokes and inspects tokens and
+	// starts the device flow.
+	googleHost = "oauth2.googleapis.com"
+	// certsHost and certsPath are where googleauth fetches the keys of
+	// Google's ID tokens.
+	certsHost = "www.googleapis.com"
+	certsPath = "/oauth2/v3/certs"
+
+	issuer          = "https://accounts.google.com"
+	keyID           = "googleauthtest"
+	deviceGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+// signingKey signs the ID tokens of every Server. googleauth caches
+// Google's keys for the whole process, so the Servers of a test binary
+// share one.
+var (
+	signingKeyOnce sync.Once
+	signingKey     *rsa.PrivateKey
+)
+
+func idTokenKey() *rsa.PrivateKey {
+	signingKeyOnce.Do(func() {
+		k, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			panic("googleauthtest: generating signing key: " + err.Error())
+		}
+		signingKey = k
+	})
+
+	return signingKey
+}
+
+// A Server is a fake authorization and token endpoint. It issues codes
+// and tokens for the client of Secret, and answers the revocation,
+// tokeninfo, device authorization and key set requests googleauth sends
+// to Google when they are made with HTTPClient. It is safe for concurrent
+// use.
+type Server struct {
+	// URL is the base URL of the server.
+	URL string
+
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	lifetime time.Duration
+	rotate   bool
+	subject  string
+	email    string
+	n        int
+	codes    map[string]grant  // by authorization code
+	devices  map[string]grant  // by device code
+	refresh  map[string]grant  // by refresh token
+	access   map[string]access // by access token
+	counts   map[string]int    // by grant type
+}
+
+type grant struct {
+	scope       string
+	redirectURI string
+	challenge   string
+	subject     string
+	email       string
+}
+
+type access struct {
+	scope  string
+	email  string
+	expiry time.Time
+}
+
+// NewServer starts a Server. Its access tokens last an hour unless
+// SetTokenLifetime changes it, and are granted by the user of SetUser.
+func NewServer() *Server {
+	s := &Server{
+		lifetime: time.Hour,
+		subject:  "googleauthtest-user",
+		email:    "user@example.com",
+		codes:    make(map[string]grant),
+		devices:  make(map[string]grant),
+		refresh:  make(map[string]grant),
+		access:   make(map[string]access),
+		counts:   make(map[string]int),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/auth", s.handleAuth)
+	mux.HandleFunc("/token", s.handleToken)
+	mux.HandleFunc("/revoke", s.handleRevoke)
+	mux.HandleFunc("/tokeninfo", s.handleTokenInfo)
+	mux.HandleFunc("/device/code", s.handleDeviceCode)
+	mux.HandleFunc(certsPath, handleCerts)
+	s.srv = httptest.NewServer(mux)
+	s.URL = s.srv.URL
+
+	return s
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Secret returns an installed client secret whose endpoints are the
+// server's.
+func (s *Server) Secret() []byte {
+	return []byte(fmt.Sprintf(`{"installed":{"client_id":%q,"client_secret":%q,`+
+		`"redirect_uris":["http://localhost"],"auth_uri":%q,"token_uri":%q}}`,
+		ClientID, ClientSecret, s.URL+"/auth", s.URL+"/token"))
+}
+
+// HTTPClient returns a client that sends the requests meant for Google's
+// OAuth endpoints to the server, and all others on as usual.
+func (s *Server) HTTPClient() *http.Client {
+	return &http.Client{Transport: &redirectTransport{host: strings.TrimPrefix(s.URL, "http://")}}
+}
+
+// AuthCodeHandler approves the consent page at once, as a user would, and
+// returns the address the browser is redirected to.
+func (s *Server) AuthCodeHandler() googleauth.AuthCodeHandler {
+	return s.approve
+}
+
+// Options returns the options that point an Authenticator at the server:
+// its HTTP client, its AuthCodeHandler and a fresh in-memory token store.
+func (s *Server) Options() []googleauth.Option {
+	return []googleauth.Option{
+		googleauth.WithHTTPClient(s.HTTPClient()),
+		googleauth.WithAuthHandler(s.AuthCodeHandler()),
+		googleauth.WithTokenStore(&googleauth.MemoryStore{}),
+	}
+}
+
+// SetTokenLifetime sets the lifetime of the access tokens issued from now
+// on. A short one makes clients refresh soon.
+func (s *Server) SetTokenLifetime(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lifetime = d
+}
+
+// SetUser sets the account that grants consent from now on: the sub and
+// email claims of its ID tokens and the email tokeninfo reports. The
+// default is googleauthtest-user, user@example.com.
+func (s *Server) SetUser(subject, email string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subject, s.email = subject, email
+}
+
+// SetRefreshTokenRotation makes every refresh from now on issue a new
+// refresh token and invalidate the one used, as Google may do.
+func (s *Server) SetRefreshTokenRotation(on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rotate = on
+}
+
+// RevokeAll revokes every token issued, as if the user withdrew consent.
+// Refreshing then fails with invalid_grant.
+func (s *Server) RevokeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh = make(map[string]grant)
+	s.access = make(map[string]access)
+}
+
+// Exchanges returns how many authorization codes were exchanged.
+func (s *Server) Exchanges() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.counts["authorization_code"]
+}
+
+// DeviceApprovals returns how many device codes were exchanged.
+func (s *Server) DeviceApprovals() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.counts[deviceGrantType]
+}
+
+// Refreshes returns how many refresh requests succeeded.
+func (s *Server) Refreshes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.counts["refresh_token"]
+}
+
+func (s *Server) approve(ctx context.Context, authURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", authURL, nil)
+	if err != nil {
+		return "", err
+	}
+	c := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := c.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		return "", fmt.Errorf("googleauthtest: consent page: %s", resp.Status)
+	}
+
+	return resp.Header.Get("Location"), nil
+}
+
+func (s *Server) handleAuth(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != ClientID || q.Get("response_type") != "code" {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirect.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if q.Get("code_challenge") != "" && q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "unsupported code_challenge_method", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	code := s.newID("code")
+	s.codes[code] = grant{
+		scope:       q.Get("scope"),
+		redirectURI: q.Get("redirect_uri"),
+		challenge:   q.Get("code_challenge"),
+		subject:     s.subject,
+		email:       s.email,
+	}
+	s.mu.Unlock()
+
+	rq := redirect.Query()
+	rq.Set("code", code)
+	rq.Set("state", q.Get("state"))
+	redirect.RawQuery = rq.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+	id, secret, ok := r.BasicAuth()
+	if ok {
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if id != ClientID || secret != ClientSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var g grant
+	var refresh string
+	switch typ := r.PostForm.Get("grant_type"); typ {
+	case "authorization_code":
+		code := r.PostForm.Get("code")
+		var ok bool
+		g, ok = s.codes[code]
+		delete(s.codes, code)
+		if !ok || g.redirectURI != r.PostForm.Get("redirect_uri") || !verifierMatches(g.challenge, r.PostForm.Get("code_verifier")) {
+			tokenError(w, "invalid_grant")
+			return
+		}
+		refresh = s.newID("refresh")
+		s.refresh[refresh] = g
+		s.counts[typ]++
+	case deviceGrantType:
+		code := r.PostForm.Get("device_code")
+		var ok bool
+		g, ok = s.devices[code]
+		delete(s.devices, code)
+		if !ok {
+			tokenError(w, "expired_token")
+			return
+		}
+		refresh = s.newID("refresh")
+		s.refresh[refresh] = g
+		s.counts[typ]++
+	case "refresh_token":
+		old := r.PostForm.Get("refresh_token")
+		var ok bool
+		g, ok = s.refresh[old]
+		if !ok {
+			tokenError(w, "invalid_grant")
+			return
+		}
+		if s.rotate {
+			delete(s.refresh, old)
+			refresh = s.newID("refresh")
+			s.refresh[refresh] = g
+		}
+		s.counts[typ]++
+	default:
+		tokenError(w, "unsupported_grant_type")
+		return
+	}
+
+	at := s.newID("access")
+	s.access[at] = access{scope: g.scope, email: g.email, expiry: time.Now().Add(s.lifetime)}
+	resp := map[string]interface{}{
+		"access_token": at,
+		"token_type":   "Bearer",
+		"expires_in":   int(s.lifetime / time.Second),
+		"scope":        g.scope,
+	}
+	if refresh != "" {
+		resp["refresh_token"] = refresh
+	}
+	if hasScope(g.scope, "openid") {
+		idToken, err := s.idToken(g)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp["id_token"] = idToken
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	tok := r.FormValue("token")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.refresh[tok]; ok {
+		delete(s.refresh, tok)
+		return
+	}
+	if _, ok := s.access[tok]; ok {
+		delete(s.access, tok)
+		return
+	}
+	tokenError(w, "invalid_token")
+}
+
+func (s *Server) handleTokenInfo(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	a, ok := s.access[r.FormValue("access_token")]
+	s.mu.Unlock()
+	if !ok || time.Now().After(a.expiry) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error_description": "Invalid Value"})
+		return
+	}
+	info := map[string]string{
+		"aud":   ClientID,
+		"scope": a.scope,
+		"exp":   strconv.FormatInt(a.expiry.Unix(), 10),
+	}
+	if hasScope(a.scope, "email") {
+		info["email"] = a.email
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+// handleDeviceCode starts the device flow. The device code is approved
+// at once, so the first poll of the token endpoint succeeds.
+func (s *Server) handleDeviceCode(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("client_id") != ClientID {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	s.mu.Lock()
+	code := s.newID("device")
+	s.devices[code] = grant{scope: r.FormValue("scope"), subject: s.subject, email: s.email}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"device_code":      code,
+		"user_code":        "GAT-TEST",
+		"verification_url": "https://www.google.com/device",
+		"expires_in":       1800,
+		"interval":         1,
+	})
+}
+
+// handleCerts serves the key set of the ID tokens, as Google's OAuth 2.0
+// certificates endpoint does.
+func handleCerts(w http.ResponseWriter, r *http.Request) {
+	pub := idTokenKey().PublicKey
+	b64 := base64.RawURLEncoding.EncodeToString
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": keyID,
+			"n":   b64(pub.N.Bytes()),
+			"e":   b64(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// idToken returns a signed ID token for g. s.mu must be held.
+func (s *Server) idToken(g grant) (string, error) {
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss": issuer,
+		"aud": ClientID,
+		"azp": ClientID,
+		"sub": g.subject,
+		"iat": now.Unix(),
+		"exp": now.Add(s.lifetime).Unix(),
+	}
+	if hasScope(g.scope, "email") {
+		claims["email"] = g.email
+		claims["email_verified"] = true
+	}
+	h, err := json.Marshal(map[string]string{"alg": "RS256", "kid": keyID, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signing := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	sum := sha256.Sum256([]byte(signing))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, idTokenKey(), crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signing + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// hasScope reports whether the space-separated scopes include want.
+// Google's short names stand for the full userinfo scopes.
+func hasScope(scopes, want string) bool {
+	for _, sc := range strings.Fields(scopes) {
+		if sc == want || sc == "https://www.googleapis.com/auth/userinfo."+want {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newID returns a fresh token or code of kind. s.mu must be held.
+func (s *Server) newID(kind string) string {
+	s.n++
+
+	return fmt.Sprintf("googleauthtest-%s-%d", kind, s.n)
+}
+
+// verifierMatches checks a PKCE code verifier against the S256 challenge,
+// if the authorization request had one.
+func verifierMatches(challenge, verifier string) bool {
+	if challenge == "" {
+		return verifier == ""
+	}
+	sum := sha256.Sum256([]byte(verifier))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:]) == challenge
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+// redirectTransport sends requests for Google's OAuth host, and for the
+// keys of its ID tokens, to host.
+type redirectTransport struct {
+	host string
+}
+
+func (t *redirectTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.URL.Host != googleHost && (r.URL.Host != certsHost || r.URL.Path != certsPath) {
+		return http.DefaultTransport.RoundTrip(r)
+	}
+	r = r.Clone(r.Context())
+	r.URL.Scheme = "http"
+	r.URL.Host = t.host
+	r.Host = ""
+
+	return http.DefaultTransport.RoundTrip(r)
+}
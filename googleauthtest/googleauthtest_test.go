@@ -0,0 +1,103 @@
+package googleauthtest_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/jarodmeng/googleauth/v2"
+	"github.com/jarodmeng/googleauth/v2/googleauthtest"
+)
+
+func TestServerAuthCode(t *testing.T) {
+	ctx := context.Background()
+	srv := googleauthtest.NewServer()
+	defer srv.Close()
+	a, err := googleauth.NewAuthenticator(ctx, append(srv.Options(),
+		googleauth.WithSecret(srv.Secret()), googleauth.WithScopes("email"))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Token(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.RefreshNow(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if e, r := srv.Exchanges(), srv.Refreshes(); e != 1 || r != 1 {
+		t.Fatalf("%d exchanges and %d refreshes, want 1 and 1", e, r)
+	}
+
+	info, err := a.VerifyToken(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Audience != googleauthtest.ClientID || info.Email != "user@example.com" {
+		t.Fatalf("VerifyToken = %+v", info)
+	}
+
+	if err := a.Revoke(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.VerifyToken(ctx); err == nil {
+		t.Fatal("token still valid after Revoke")
+	}
+}
+
+func TestServerIDToken(t *testing.T) {
+	ctx := context.Background()
+	srv := googleauthtest.NewServer()
+	defer srv.Close()
+	srv.SetUser("42", "someone@example.com")
+	a, err := googleauth.NewAuthenticator(ctx, append(srv.Options(),
+		googleauth.WithSecret(srv.Secret()), googleauth.WithOpenID())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := a.UserInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Subject != "42" || info.Email != "someone@example.com" || !info.EmailVerified {
+		t.Fatalf("UserInfo = %+v", info)
+	}
+}
+
+func TestServerDevice(t *testing.T) {
+	ctx := context.Background()
+	srv := googleauthtest.NewServer()
+	defer srv.Close()
+	a, err := googleauth.NewAuthenticator(ctx,
+		googleauth.WithSecret(srv.Secret()),
+		googleauth.WithHTTPClient(srv.HTTPClient()),
+		googleauth.WithoutCache(),
+		googleauth.WithFlow(googleauth.FlowDevice),
+		googleauth.WithPrompter(&googleauth.TerminalPrompter{Out: ioutil.Discard}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Token(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.DeviceApprovals(); n != 1 {
+		t.Fatalf("%d device approvals, want 1", n)
+	}
+}
+
+func TestServerRevokeAll(t *testing.T) {
+	ctx := context.Background()
+	srv := googleauthtest.NewServer()
+	defer srv.Close()
+	a, err := googleauth.NewAuthenticator(ctx, append(srv.Options(), googleauth.WithSecret(srv.Secret()))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Token(ctx); err != nil {
+		t.Fatal(err)
+	}
+	srv.RevokeAll()
+	if _, err := a.RefreshNow(ctx); !errors.Is(err, googleauth.ErrReauthorizationRequired) {
+		t.Fatalf("RefreshNow = %v, want ErrReauthorizationRequired", err)
+	}
+}
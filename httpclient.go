@@ -0,0 +1,98 @@
+package googleauth
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+
+	"golang.org/x/oauth2"
+)
+
+// WithHTTPClient sets the HTTP client used for the token exchange,
+// refreshes, revocation and the other calls to Google, and whose Transport
+// carries the requests of the returned Client. Corporate networks can use
+// it for a proxy or custom CA certificates. A client set on the context
+// with oauth2.HTTPClient takes precedence. Under WithFIPSMode or
+// WithDeviceCertificate its Transport must be nil or an *http.Transport.
+func WithHTTPClient(c *http.Client) Option {
+	return func(s *settings) {
+		s.httpClient = c
+	}
+}
+
+var errCustomTransport = errors.New("googleauth: TLS settings need an *http.Transport in the HTTP client")
+
+// newHTTPClient builds the client the Authenticator uses for calls to
+// Google, or returns nil when the settings need nothing beyond the
+// default. Under WithDPoP it also returns the transport adding the
+// proofs, to be told the token endpoint.
+func newHTTPClient(s *settings) (*http.Client, *dpopTransport, error) {
+	if !s.fips && s.dpop == nil && !s.deviceCert {
+		return s.httpClient, nil, nil
+	}
+
+	c := &http.Client{}
+	var base http.RoundTripper = http.DefaultTransport
+	if s.httpClient != nil {
+		*c = *s.httpClient
+		if c.Transport != nil {
+			base = c.Transport
+		}
+	}
+	if !s.fips && !s.deviceCert {
+		dt := &dpopTransport{key: s.dpop, base: base}
+		c.Transport = dt
+		return c, dt, nil
+	}
+	ht, ok := base.(*http.Transport)
+	if !ok {
+		return nil, nil, errCustomTransport
+	}
+
+	t := ht.Clone()
+	if s.fips {
+		cfg := fipsTLSConfig()
+		if t.TLSClientConfig != nil {
+			// Keep custom CA certificates.
+			cfg.RootCAs = t.TLSClientConfig.RootCAs
+		}
+		t.TLSClientConfig = cfg
+	}
+	if s.deviceCert {
+		src, err := newDeviceCertSource()
+		if err != nil {
+			return nil, nil, err
+		}
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = &tls.Config{}
+		}
+		t.TLSClientConfig.GetClientCertificate = src.get
+	}
+
+	var rt http.RoundTripper = t
+	var dt *dpopTransport
+	if s.dpop != nil {
+		dt = &dpopTransport{key: s.dpop, base: rt}
+		rt = dt
+	}
+	// Outermost, so DPoP proofs name the endpoint actually called.
+	if s.deviceCert {
+		rt = &mtlsTransport{base: rt}
+	}
+
+	c.Transport = rt
+
+	return c, dt, nil
+}
+
+// withHTTPClient makes the Authenticator's HTTP client, if it has one,
+// the client used for calls made with ctx by this package and by the
+// oauth2 package, unless the caller already set one with oauth2.HTTPClient.
+func (a *Authenticator) withHTTPClient(ctx context.Context) context.Context {
+	if a.hc == nil || ctx.Value(oauth2.HTTPClient) != nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, oauth2.HTTPClient, a.hc)
+}
@@ -0,0 +1,59 @@
+package googleauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const iamCredentialsURL = "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/"
+
+// iamCall invokes method of the IAM Credentials API for the service
+// account email, decoding the response into out. The caller needs the
+// Service Account Token Creator role on the account.
+func iamCall(ctx context.Context, client *http.Client, email, method string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		iamCredentialsURL+url.PathEscape(email)+":"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	err = doGoogleJSON(client, req, out)
+	if err != nil {
+		return fmt.Errorf("googleauth: %s for %s: %v", method, email, err)
+	}
+
+	return nil
+}
+
+// doGoogleJSON sends req and decodes the JSON response into out if it
+// isn't nil. Failures carry the message from Google's error body.
+func doGoogleJSON(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&e)
+		return fmt.Errorf("%s: %s", resp.Status, e.Error.Message)
+	}
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
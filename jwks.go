@@ -0,0 +1,247 @@
+package googleauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// googleCertsURL serves the keys Google signs its ID tokens with.
+const googleCertsURL = "https://www.googleapis.com/oauth2/v3/certs"
+
+// clockSkew is how far token timestamps may be off from the local clock.
+const clockSkew = time.Minute
+
+// minRefetch is the least time between fetches of a key set. Key IDs come
+// from tokens anyone can send, so they must not trigger a fetch each.
+const minRefetch = time.Minute
+
+// IDTokenClaims are the claims of a verified ID token.
+type IDTokenClaims struct {
+	Issuer          string `json:"iss"`
+	Subject         string `json:"sub"`
+	Audience        string `json:"-"` // the first if there are several
+	AuthorizedParty string `json:"azp"`
+	Expiry          int64  `json:"exp"`
+	IssuedAt        int64  `json:"iat"`
+	Email           string `json:"email"`
+	EmailVerified   bool   `json:"email_verified"`
+
+	// Claims holds every claim of the token, including the above.
+	Claims map[string]interface{} `json:"-"`
+}
+
+// keySet is a cached JSON Web Key Set.
+type keySet struct {
+	url string
+
+	mu       sync.Mutex
+	keys     map[string]*rsa.PublicKey
+	expiry   time.Time
+	fetched  time.Time     // when the last fetch started
+	err      error         // of the last fetch
+	fetching chan struct{} // closed when the fetch in progress ends
+}
+
+var googleKeys = &keySet{url: googleCertsURL}
+
+// key returns the key with ID kid, fetching the set when it has expired or
+// lacks the key, as after Google rotates its keys, but not more than once
+// every minRefetch. Verifications wait for a fetch in progress rather than
+// start their own.
+func (s *keySet) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	for {
+		s.mu.Lock()
+		k, ok := s.keys[kid]
+		now := time.Now()
+		if ok && now.Before(s.expiry) {
+			s.mu.Unlock()
+			return k, nil
+		}
+		if ch := s.fetching; ch != nil {
+			s.mu.Unlock()
+			select {
+			case <-ch:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		if now.Sub(s.fetched) < minRefetch {
+			err := s.err
+			s.mu.Unlock()
+			switch {
+			case ok:
+				// Expired, but as recent as the set gets.
+				return k, nil
+			case err != nil:
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidIDToken, kid)
+		}
+		ch := make(chan struct{})
+		s.fetching, s.fetched = ch, now
+		s.mu.Unlock()
+
+		keys, expiry, err := s.fetch(ctx)
+		s.mu.Lock()
+		if err == nil {
+			s.keys, s.expiry = keys, expiry
+		}
+		s.err, s.fetching = err, nil
+		s.mu.Unlock()
+		close(ch)
+	}
+}
+
+func (s *keySet) fetch(ctx context.Context) (map[string]*rsa.PublicKey, time.Time, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.url, nil)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	resp, err := contextClient(ctx).Do(req)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, time.Time{}, fmt.Errorf("googleauth: fetching %s: %s", s.url, resp.Status)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, time.Time{}, err
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	return keys, time.Now().Add(maxAge(resp.Header.Get("Cache-Control"), time.Hour)), nil
+}
+
+// maxAge returns the max-age of a Cache-Control header, or def.
+func maxAge(cc string, def time.Duration) time.Duration {
+	for _, d := range strings.Split(cc, ",") {
+		d = strings.TrimSpace(d)
+		if strings.HasPrefix(d, "max-age=") {
+			if n, err := strconv.Atoi(strings.TrimPrefix(d, "max-age=")); err == nil {
+				return time.Duration(n) * time.Second
+			}
+		}
+	}
+
+	return def
+}
+
+// verifyJWT checks the RS256 signature of the compact JWT raw against
+// keys and its validity period, and returns its claims.
+func verifyJWT(ctx context.Context, keys *keySet, raw string) (*IDTokenClaims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidIDToken)
+	}
+	var hdr struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &hdr); err != nil {
+		return nil, err
+	}
+	if hdr.Alg != "RS256" {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidIDToken, hdr.Alg)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	k, err := keys.key(ctx, hdr.Kid)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, h[:], sig); err != nil {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidIDToken)
+	}
+
+	c := &IDTokenClaims{}
+	if err := decodeSegment(parts[1], c); err != nil {
+		return nil, err
+	}
+	if err := decodeSegment(parts[1], &c.Claims); err != nil {
+		return nil, err
+	}
+	if aud := audiences(c.Claims); len(aud) > 0 {
+		c.Audience = aud[0]
+	}
+	now := time.Now()
+	if now.After(time.Unix(c.Expiry, 0).Add(clockSkew)) {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidIDToken)
+	}
+	if now.Add(clockSkew).Before(time.Unix(c.IssuedAt, 0)) {
+		return nil, fmt.Errorf("%w: issued in the future", ErrInvalidIDToken)
+	}
+
+	return c, nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err == nil {
+		err = json.Unmarshal(b, v)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	return nil
+}
+
+// audiences returns the aud claim, which may be a string or a list.
+func audiences(claims map[string]interface{}) []string {
+	switch v := claims["aud"].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var aud []string
+		for _, a := range v {
+			if s, ok := a.(string); ok {
+				aud = append(aud, s)
+			}
+		}
+		return aud
+	}
+
+	return nil
+}
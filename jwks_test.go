@@ -0,0 +1,42 @@
+package googleauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestKeySetRefetchLimit(t *testing.T) {
+	var fetches int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		fmt.Fprint(w, `{"keys":[{"kty":"RSA","kid":"k1","n":"AQAB","e":"AQAB"}]}`)
+	}))
+	defer srv.Close()
+	s := &keySet{url: srv.URL}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.key(ctx, fmt.Sprintf("unknown-%d", i))
+			if !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("key = %v, want ErrInvalidIDToken", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if _, err := s.key(ctx, "k1"); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Fatalf("%d fetches, want 1", n)
+	}
+}
@@ -0,0 +1,103 @@
+//go:build !js
+
+package googleauth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/oauth2"
+)
+
+// defaultKeyringService names the keyring entries of a KeyringStore with
+// no Service.
+const defaultKeyringService = "googleauth"
+
+// cacheKeyService names the keyring entries holding the keys of
+// WithKeyringCacheKey, one per cache directory.
+const cacheKeyService = "googleauth-cache-key"
+
+// KeyringStore keeps tokens in the operating system's keyring: the
+// Keychain on macOS, the Secret Service (GNOME Keyring, KWallet) on Linux
+// and the Credential Manager on Windows. Each token is an entry of Service
+// whose account is the key. An empty Service means "googleauth".
+//
+// Keyrings cannot be enumerated, so KeyringStore is not a TokenLister.
+type KeyringStore struct {
+	Service string
+}
+
+func (s *KeyringStore) service() string {
+	if s.Service == "" {
+		return defaultKeyringService
+	}
+
+	return s.Service
+}
+
+// Get implements TokenStore.
+func (s *KeyringStore) Get(key string) (*oauth2.Token, error) {
+	v, err := keyring.Get(s.service(), key)
+	if err == keyring.ErrNotFound {
+		return nil, ErrTokenNotCached
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeToken([]byte(v))
+}
+
+// Put implements TokenStore.
+func (s *KeyringStore) Put(key string, tok *oauth2.Token) error {
+	b, err := encodeToken(tok)
+	if err != nil {
+		return err
+	}
+
+	return keyring.Set(s.service(), key, string(b))
+}
+
+// Delete implements TokenStore.
+func (s *KeyringStore) Delete(key string) error {
+	err := keyring.Delete(s.service(), key)
+	if err == keyring.ErrNotFound {
+		return nil
+	}
+
+	return err
+}
+
+// WithKeyringCacheKey is like WithCacheKey with a random key kept in the
+// operating system's keyring, created the first time the cache directory
+// is used.
+func WithKeyringCacheKey() Option {
+	return func(s *settings) {
+		s.cacheKey = keyringCacheKey
+	}
+}
+
+// KeyringCacheKey is equivalent to WithKeyringCacheKey.
+func (b *Builder) KeyringCacheKey() *Builder {
+	return b.With(WithKeyringCacheKey())
+}
+
+func keyringCacheKey(dir string) ([]byte, error) {
+	v, err := keyring.Get(cacheKeyService, dir)
+	if err == nil {
+		return base64.StdEncoding.DecodeString(v)
+	}
+	if err != keyring.ErrNotFound {
+		return nil, err
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := keyring.Set(cacheKeyService, dir, base64.StdEncoding.EncodeToString(key)); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
@@ -0,0 +1,131 @@
+package googleauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/oauth2/google"
+)
+
+const iamURL = "https://iam.googleapis.com/v1/"
+
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// RotateKey replaces the service account key in keyFile with a new key of
+// the same service account. It creates the key through the IAM API, swaps
+// the file atomically, checks that the new key can obtain tokens, and only
+// then deletes the old key. If the new key doesn't work, the old file is
+// put back. It returns the ID of the new key.
+//
+// client makes the IAM calls and needs the Service Account Key Admin role
+// on the account; nil uses the old key itself.
+func RotateKey(ctx context.Context, client *http.Client, keyFile string) (string, error) {
+	old, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return "", err
+	}
+	conf, err := google.JWTConfigFromJSON(old, cloudPlatformScope)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidSecret, err)
+	}
+	if client == nil {
+		client = conf.Client(ctx)
+	}
+
+	var created struct {
+		Name           string `json:"name"`
+		PrivateKeyData string `json:"privateKeyData"`
+	}
+	err = iamRequest(ctx, client, "POST", "projects/-/serviceAccounts/"+url.PathEscape(conf.Email)+"/keys", struct{}{}, &created)
+	if err != nil {
+		return "", err
+	}
+	key, err := base64.StdEncoding.DecodeString(created.PrivateKeyData)
+	if err != nil {
+		return "", err
+	}
+	newConf, err := google.JWTConfigFromJSON(key, cloudPlatformScope)
+	if err != nil {
+		return "", err
+	}
+
+	if err := writeFileAtomic(keyFile, key); err != nil {
+		iamRequest(ctx, client, "DELETE", created.Name, nil, nil)
+		return "", err
+	}
+	if err := verifyKey(ctx, key); err != nil {
+		if werr := writeFileAtomic(keyFile, old); werr != nil {
+			return "", fmt.Errorf("googleauth: new key %s doesn't work (%v) and the old key cannot be restored: %v",
+				newConf.PrivateKeyID, err, werr)
+		}
+		iamRequest(ctx, client, "DELETE", created.Name, nil, nil)
+		return "", fmt.Errorf("googleauth: new key doesn't work: %v", err)
+	}
+
+	// The rotation is complete once the new key is in place; the old one
+	// only remains valid longer than intended if deleting it fails.
+	oldName := "projects/-/serviceAccounts/" + url.PathEscape(conf.Email) + "/keys/" + conf.PrivateKeyID
+	if err := iamRequest(ctx, client, "DELETE", oldName, nil, nil); err != nil {
+		return newConf.PrivateKeyID, fmt.Errorf("googleauth: rotated to key %s but cannot delete old key %s: %v",
+			newConf.PrivateKeyID, conf.PrivateKeyID, err)
+	}
+
+	return newConf.PrivateKeyID, nil
+}
+
+// verifyKey obtains a token with key, retrying while the new key
+// propagates, which can take a minute.
+func verifyKey(ctx context.Context, key []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	conf, err := google.JWTConfigFromJSON(key, cloudPlatformScope)
+	if err != nil {
+		return err
+	}
+	for {
+		_, err = conf.TokenSource(ctx).Token()
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// iamRequest calls the IAM API method at path with the JSON body in,
+// decoding the response into out if it isn't nil.
+func iamRequest(ctx context.Context, client *http.Client, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, iamURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	err = doGoogleJSON(client, req, out)
+	if err != nil {
+		return fmt.Errorf("googleauth: IAM %s %s: %v", method, path, err)
+	}
+
+	return nil
+}
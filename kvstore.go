@@ -0,0 +1,79 @@
+package googleauth
+
+import (
+	"sort"
+
+	"golang.org/x/oauth2"
+)
+
+// KeyValue is a minimal byte-oriented storage interface. Its method set is
+// restricted to types gomobile can bind, so Android and iOS apps can
+// implement it on top of SharedPreferences, the Keychain or any other
+// sandboxed storage and pass it to NewKeyValueStore.
+type KeyValue interface {
+	// Get returns the value stored under key, or nil if there is none.
+	Get(key string) ([]byte, error)
+	Put(key string, value []byte) error
+	Delete(key string) error
+}
+
+// KeyValueStore is a TokenStore that keeps JSON-encoded tokens in a
+// KeyValue.
+type KeyValueStore struct {
+	kv KeyValue
+}
+
+// NewKeyValueStore returns a TokenStore backed by kv.
+func NewKeyValueStore(kv KeyValue) *KeyValueStore {
+	return &KeyValueStore{kv: kv}
+}
+
+// Get implements TokenStore.
+func (s *KeyValueStore) Get(key string) (*oauth2.Token, error) {
+	b, err := s.kv.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, ErrTokenNotCached
+	}
+
+	return decodeToken(b)
+}
+
+// Put implements TokenStore.
+func (s *KeyValueStore) Put(key string, tok *oauth2.Token) error {
+	b, err := encodeToken(tok)
+	if err != nil {
+		return err
+	}
+
+	return s.kv.Put(key, b)
+}
+
+// Delete implements TokenStore.
+func (s *KeyValueStore) Delete(key string) error {
+	return s.kv.Delete(key)
+}
+
+// Keys implements TokenLister if the KeyValue has a method
+// Keys() ([]string, error); otherwise it fails with ErrNotListable.
+func (s *KeyValueStore) Keys() ([]string, error) {
+	l, ok := s.kv.(interface{ Keys() ([]string, error) })
+	if !ok {
+		return nil, ErrNotListable
+	}
+	all, err := l.Keys()
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, k := range all {
+		if !hiddenKey(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
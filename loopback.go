@@ -0,0 +1,157 @@
+package googleauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// LoopbackReceiver receives the authorization code on a temporary HTTP
+// server listening on 127.0.0.1, so the user doesn't have to copy it.
+// The OAuth client must be of the "Desktop app" type.
+type LoopbackReceiver struct {
+	// Port is the port to listen on. Zero picks a free port.
+	Port int
+	// Opener shows the consent page. Nil means the system browser.
+	Opener URLOpener
+	// Prompter is told about the consent page and the wait for the
+	// redirect. Nil means a TerminalPrompter.
+	Prompter Prompter
+	// Messages are the texts shown. Nil means the environment's language.
+	Messages *Messages
+
+	noBrowser bool // only print the consent page address
+}
+
+// ReceiveCode implements CodeReceiver.
+func (l *LoopbackReceiver) ReceiveCode(ctx context.Context, req *AuthRequest) (string, error) {
+	msgs := messagesOr(l.Messages)
+	prompter := l.Prompter
+	if prompter == nil {
+		prompter = &TerminalPrompter{Messages: msgs}
+	}
+
+	ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(l.Port)))
+	if err != nil {
+		return "", err
+	}
+	req.Config.RedirectURL = "http://" + ln.Addr().String() + "/"
+	if onCrostini() {
+		// ChromeOS forwards localhost, but not 127.0.0.1, from its
+		// browser to ports listening in the Linux container.
+		port := ln.Addr().(*net.TCPAddr).Port
+		req.Config.RedirectURL = "http://localhost:" + strconv.Itoa(port) + "/"
+	}
+
+	redirect, err := url.Parse(req.Config.RedirectURL)
+	if err != nil {
+		return "", err
+	}
+
+	results := make(chan codeResult, 1)
+	var done int32
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := checkCallback(r, redirect); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		q := r.URL.Query()
+		if q.Get("state") != req.State {
+			http.Error(w, "state mismatch", http.StatusBadRequest)
+			return
+		}
+		var res codeResult
+		switch {
+		case q.Get("error") != "":
+			res.err = fmt.Errorf("googleauth: authorization failed: %s", q.Get("error"))
+		case q.Get("code") != "":
+			res.code = q.Get("code")
+		default:
+			http.Error(w, "missing code", http.StatusBadRequest)
+			return
+		}
+		// Only the first valid callback counts; repeated or late ones,
+		// whether from a reload or another process, are turned away.
+		if !atomic.CompareAndSwapInt32(&done, 0, 1) {
+			http.Error(w, "authorization already completed", http.StatusGone)
+			return
+		}
+		if res.err != nil {
+			fmt.Fprintln(w, msgs.PageFailed)
+		} else {
+			fmt.Fprintln(w, msgs.PageComplete)
+		}
+		results <- res
+	})}
+	go srv.Serve(ln)
+	defer func() {
+		// Let the result page reach the browser before closing.
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	authURL := req.AuthCodeURL()
+	if l.Opener != nil {
+		if err := l.Opener.OpenURL(authURL); err != nil {
+			return "", err
+		}
+		prompter.ShowConsentURL(authURL, true)
+	} else {
+		prompter.ShowConsentURL(authURL, !l.noBrowser && openBrowser(authURL) == nil)
+	}
+	prompter.ShowProgress(msgs.WaitingForBrowser)
+
+	select {
+	case res := <-results:
+		return res.code, res.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// checkCallback rejects requests to the loopback server that cannot be the
+// browser following Google's redirect: wrong method or path, a Host other
+// than the redirect's (DNS rebinding), a peer or local address that isn't
+// loopback, or fetch metadata showing a script rather than a navigation.
+func checkCallback(r *http.Request, redirect *url.URL) error {
+	if r.Method != http.MethodGet {
+		return errors.New("method not allowed")
+	}
+	if r.URL.Path != redirect.Path {
+		return errors.New("unexpected path")
+	}
+	if r.Host != redirect.Host {
+		return errors.New("unexpected host")
+	}
+	if !isLoopbackAddr(r.RemoteAddr) {
+		return errors.New("unexpected peer")
+	}
+	if la, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok && !isLoopbackAddr(la.String()) {
+		return errors.New("unexpected interface")
+	}
+	if mode := r.Header.Get("Sec-Fetch-Mode"); mode != "" && mode != "navigate" {
+		return errors.New("not a navigation")
+	}
+	if origin := r.Header.Get("Origin"); origin != "" && origin != "null" {
+		return errors.New("unexpected origin")
+	}
+
+	return nil
+}
+
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
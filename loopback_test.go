@@ -0,0 +1,117 @@
+package googleauth
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestCheckCallback(t *testing.T) {
+	redirect, _ := url.Parse("http://127.0.0.1:4321/")
+	local := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 4321}
+	tests := []struct {
+		name   string
+		modify func(r *http.Request) *http.Request
+		ok     bool
+	}{
+		{"valid", func(r *http.Request) *http.Request { return r }, true},
+		{"method", func(r *http.Request) *http.Request { r.Method = "POST"; return r }, false},
+		{"path", func(r *http.Request) *http.Request { r.URL.Path = "/other"; return r }, false},
+		{"host", func(r *http.Request) *http.Request { r.Host = "evil.example:4321"; return r }, false},
+		{"peer", func(r *http.Request) *http.Request { r.RemoteAddr = "192.0.2.1:5555"; return r }, false},
+		{"interface", func(r *http.Request) *http.Request {
+			la := &net.TCPAddr{IP: net.ParseIP("192.0.2.2"), Port: 4321}
+			return r.WithContext(context.WithValue(r.Context(), http.LocalAddrContextKey, la))
+		}, false},
+		{"fetch mode", func(r *http.Request) *http.Request { r.Header.Set("Sec-Fetch-Mode", "cors"); return r }, false},
+		{"navigation", func(r *http.Request) *http.Request { r.Header.Set("Sec-Fetch-Mode", "navigate"); return r }, true},
+		{"origin", func(r *http.Request) *http.Request { r.Header.Set("Origin", "https://evil.example"); return r }, false},
+		{"null origin", func(r *http.Request) *http.Request { r.Header.Set("Origin", "null"); return r }, true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "http://127.0.0.1:4321/?state=s&code=c", nil)
+		r.RemoteAddr = "127.0.0.1:5555"
+		r = r.WithContext(context.WithValue(r.Context(), http.LocalAddrContextKey, local))
+		err := checkCallback(tt.modify(r), redirect)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: checkCallback = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+type openerFunc func(url string) error
+
+func (f openerFunc) OpenURL(url string) error { return f(url) }
+
+// TestLoopbackReceiverRejects sends the receiver bad callbacks before the
+// good one, and one after it, from the Opener, while the flow waits.
+func TestLoopbackReceiverRejects(t *testing.T) {
+	get := func(u string, modify func(*http.Request)) int {
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if modify != nil {
+			modify(req)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	req := &AuthRequest{
+		Config: &oauth2.Config{ClientID: "id", Endpoint: oauth2.Endpoint{AuthURL: "https://accounts.example/auth"}},
+		State:  "good-state",
+	}
+	l := &LoopbackReceiver{
+		Prompter: &TerminalPrompter{Out: ioutil.Discard},
+		Opener: openerFunc(func(string) error {
+			base := req.Config.RedirectURL
+			cb := base + "?state=good-state&code=the-code"
+			bad := []struct {
+				name   string
+				u      string
+				modify func(*http.Request)
+			}{
+				{"method", cb, func(r *http.Request) { r.Method = "POST" }},
+				{"path", strings.TrimSuffix(base, "/") + "/other?state=good-state&code=the-code", nil},
+				{"host", cb, func(r *http.Request) { r.Host = "evil.example" }},
+				{"fetch mode", cb, func(r *http.Request) { r.Header.Set("Sec-Fetch-Mode", "no-cors") }},
+				{"origin", cb, func(r *http.Request) { r.Header.Set("Origin", "https://evil.example") }},
+				{"state", base + "?state=other&code=stolen", nil},
+				{"no code", base + "?state=good-state", nil},
+			}
+			for _, b := range bad {
+				if code := get(b.u, b.modify); code != http.StatusBadRequest {
+					t.Errorf("%s: status %d, want 400", b.name, code)
+				}
+			}
+			if code := get(cb, nil); code != http.StatusOK {
+				t.Errorf("callback: status %d, want 200", code)
+			}
+			if code := get(base+"?state=good-state&code=second", nil); code != http.StatusGone {
+				t.Errorf("repeated callback: status %d, want 410", code)
+			}
+			return nil
+		}),
+	}
+
+	code, err := l.ReceiveCode(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != "the-code" {
+		t.Fatalf("code %q, want the-code", code)
+	}
+}
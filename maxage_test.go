@@ -0,0 +1,44 @@
+package googleauth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jarodmeng/googleauth/v2"
+	"github.com/jarodmeng/googleauth/v2/googleauthtest"
+)
+
+func TestMaxGrantAgeMidProcess(t *testing.T) {
+	ctx := context.Background()
+	srv := googleauthtest.NewServer()
+	defer srv.Close()
+	a, err := googleauth.NewAuthenticator(ctx, append(srv.Options(),
+		googleauth.WithSecret(srv.Secret()), googleauth.WithMaxGrantAge(time.Hour))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, err := a.TokenSource(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.Token(); err != nil {
+		t.Fatal(err)
+	}
+
+	restore := googleauth.SetTimeNow(func() time.Time { return time.Now().Add(2 * time.Hour) })
+	defer restore()
+	if _, err := ts.Token(); !errors.Is(err, googleauth.ErrConsentRequired) {
+		t.Fatalf("Token of a grant past its age = %v, want ErrConsentRequired", err)
+	}
+	if _, err := a.Token(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Exchanges(); n != 2 {
+		t.Fatalf("%d code exchanges, want 2", n)
+	}
+	if _, err := ts.Token(); err != nil {
+		t.Fatalf("source did not pick up the new grant: %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package googleauth
+
+import (
+	"os"
+	"strings"
+)
+
+// Messages are the texts googleauth shows users during the consent flow.
+// Pass a modified copy of the result of MessagesFor to WithMessages to
+// change some of them.
+type Messages struct {
+	// OpenLink precedes the consent URL when the browser wasn't opened.
+	OpenLink string
+	// EnterDeviceCode precedes the verification URL in the device flow.
+	// It is a format with the user code as its only argument.
+	EnterDeviceCode string
+	// TypeCode asks for the authorization code to be pasted.
+	TypeCode string
+	// OpenLinkTypeCode precedes the consent URL when the browser wasn't
+	// opened and the code has to be pasted.
+	OpenLinkTypeCode string
+
+	WaitingForBrowser string
+	WaitingForDevice  string
+	Exchanging        string
+
+	// Authorized and AuthorizationFailed report the end of the flow; the
+	// latter is a format with the error as its argument. ExpiresIn
+	// follows the wait for a device code, with the remaining time.
+	Authorized          string
+	AuthorizationFailed string
+	ExpiresIn           string
+
+	// PageComplete and PageFailed are shown in the browser after the
+	// redirect to the loopback server.
+	PageComplete string
+	PageFailed   string
+}
+
+var catalog = map[string]Messages{
+	"en": {
+		OpenLink:            "Go to the following link in your browser:",
+		EnterDeviceCode:     "Go to the following link on any device and enter the code %s:",
+		TypeCode:            "Type the authorization code:",
+		OpenLinkTypeCode:    "Go to the following link in your browser then type the authorization code:",
+		WaitingForBrowser:   "Waiting for authorization in the browser...",
+		WaitingForDevice:    "Waiting for authorization on the other device...",
+		Exchanging:          "Exchanging the authorization code...",
+		Authorized:          "Authorized.",
+		AuthorizationFailed: "Authorization failed: %v",
+		ExpiresIn:           "expires in %s",
+		PageComplete:        "Authorization complete. You can close this window.",
+		PageFailed:          "Authorization failed. You can close this window.",
+	},
+	"de": {
+		OpenLink:            "Öffnen Sie den folgenden Link in Ihrem Browser:",
+		EnterDeviceCode:     "Öffnen Sie den folgenden Link auf einem beliebigen Gerät und geben Sie den Code %s ein:",
+		TypeCode:            "Geben Sie den Autorisierungscode ein:",
+		OpenLinkTypeCode:    "Öffnen Sie den folgenden Link in Ihrem Browser und geben Sie dann den Autorisierungscode ein:",
+		WaitingForBrowser:   "Warten auf die Autorisierung im Browser...",
+		WaitingForDevice:    "Warten auf die Autorisierung auf dem anderen Gerät...",
+		Exchanging:          "Autorisierungscode wird eingelöst...",
+		Authorized:          "Autorisiert.",
+		AuthorizationFailed: "Autorisierung fehlgeschlagen: %v",
+		ExpiresIn:           "läuft ab in %s",
+		PageComplete:        "Autorisierung abgeschlossen. Sie können dieses Fenster schließen.",
+		PageFailed:          "Autorisierung fehlgeschlagen. Sie können dieses Fenster schließen.",
+	},
+	"es": {
+		OpenLink:            "Abre el siguiente enlace en tu navegador:",
+		EnterDeviceCode:     "Abre el siguiente enlace en cualquier dispositivo e introduce el código %s:",
+		TypeCode:            "Escribe el código de autorización:",
+		OpenLinkTypeCode:    "Abre el siguiente enlace en tu navegador y escribe el código de autorización:",
+		WaitingForBrowser:   "Esperando la autorización en el navegador...",
+		WaitingForDevice:    "Esperando la autorización en el otro dispositivo...",
+		Exchanging:          "Canjeando el código de autorización...",
+		Authorized:          "Autorizado.",
+		AuthorizationFailed: "La autorización ha fallado: %v",
+		ExpiresIn:           "caduca en %s",
+		PageComplete:        "Autorización completada. Ya puedes cerrar esta ventana.",
+		PageFailed:          "La autorización ha fallado. Ya puedes cerrar esta ventana.",
+	},
+	"fr": {
+		OpenLink:            "Ouvrez le lien suivant dans votre navigateur :",
+		EnterDeviceCode:     "Ouvrez le lien suivant sur n'importe quel appareil et saisissez le code %s :",
+		TypeCode:            "Saisissez le code d'autorisation :",
+		OpenLinkTypeCode:    "Ouvrez le lien suivant dans votre navigateur puis saisissez le code d'autorisation :",
+		WaitingForBrowser:   "En attente de l'autorisation dans le navigateur...",
+		WaitingForDevice:    "En attente de l'autorisation sur l'autre appareil...",
+		Exchanging:          "Échange du code d'autorisation...",
+		Authorized:          "Autorisé.",
+		AuthorizationFailed: "Échec de l'autorisation : %v",
+		ExpiresIn:           "expire dans %s",
+		PageComplete:        "Autorisation terminée. Vous pouvez fermer cette fenêtre.",
+		PageFailed:          "Échec de l'autorisation. Vous pouvez fermer cette fenêtre.",
+	},
+}
+
+// MessagesFor returns the messages for lang, a language tag such as "de",
+// "pt-BR" or a POSIX locale like "fr_FR.UTF-8". Unknown languages get
+// English. An empty lang selects the language of the environment, from
+// LC_ALL, LC_MESSAGES or LANG.
+func MessagesFor(lang string) *Messages {
+	if lang == "" {
+		for _, v := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+			if lang = os.Getenv(v); lang != "" {
+				break
+			}
+		}
+	}
+	lang = strings.ToLower(lang)
+	if i := strings.IndexAny(lang, "-_."); i >= 0 {
+		lang = lang[:i]
+	}
+	m, ok := catalog[lang]
+	if !ok {
+		m = catalog["en"]
+	}
+
+	return &m
+}
+
+// WithLanguage shows the consent flow's messages in lang, as selected by
+// MessagesFor. By default the environment's language is used.
+func WithLanguage(lang string) Option {
+	return func(s *settings) {
+		s.messages = MessagesFor(lang)
+	}
+}
+
+// WithMessages shows m instead of the built-in messages.
+func WithMessages(m *Messages) Option {
+	return func(s *settings) {
+		s.messages = m
+	}
+}
+
+// messagesOr returns m, or the environment's messages if m is nil.
+func messagesOr(m *Messages) *Messages {
+	if m == nil {
+		return MessagesFor("")
+	}
+
+	return m
+}
@@ -0,0 +1,276 @@
+package googleauth
+
+import (
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// An Option configures an Authenticator.
+type Option func(*settings)
+
+type settings struct {
+	secret     []byte
+	secretFile string
+	scopes     []string
+	openID     bool
+	tokenFile  string
+	profile    string
+	store      TokenStore
+	flow       Flow
+	receiver   CodeReceiver
+	port       int
+	noBrowser  bool
+	prompter   Prompter
+	timeout    time.Duration
+	retry      *RetryPolicy
+	authOpts   []oauth2.AuthCodeOption
+	exchOpts   []oauth2.AuthCodeOption
+	cacheDir   string
+	logger     Logger
+	noPrompt   bool
+	container  bool
+	adc        bool
+	strictPerm bool
+	maxAge     time.Duration
+	fips       bool
+	dpop       *DPoPKey
+	deviceCert bool
+	httpClient *http.Client
+	issuer     string
+	clientType ClientType
+	saKey      []byte
+	subject    string
+	cacheKey   func(dir string) ([]byte, error)
+	messages   *Messages
+	events     func(Event)
+	telemetry  TelemetrySink
+
+	refreshWindow time.Duration
+	refreshJitter time.Duration
+
+	err error // first error from an option, reported by NewAuthenticator
+}
+
+// newSettings applies the package defaults followed by opts.
+func newSettings(opts []Option) *settings {
+	s := &settings{}
+	for _, opt := range defaultOptions() {
+		opt(s)
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// tokenStore returns the store tokens are cached in: the one set with
+// WithTokenStore or the default in the WithCacheDir directory, encrypted
+// under WithCacheKey.
+func (s *settings) tokenStore() (TokenStore, error) {
+	store := s.store
+	if store == nil {
+		var err error
+		store, err = newDefaultStore(s.cacheDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s.cacheKey != nil {
+		return encryptCache(store, s.cacheKey)
+	}
+
+	return store, nil
+}
+
+// A Logger receives diagnostic messages about cache use and the consent
+// flow. *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// WithSecret sets the client secret JSON downloaded from the Google API
+// Console. An external_account credential configuration for workload
+// identity federation is accepted in its place, for keyless access from
+// CI systems and other clouds.
+func WithSecret(secret []byte) Option {
+	return func(s *settings) {
+		s.secret = secret
+	}
+}
+
+// WithSecretFile reads the client secret JSON from file. It is ignored if
+// WithSecret is also given.
+func WithSecretFile(file string) Option {
+	return func(s *settings) {
+		s.secretFile = file
+	}
+}
+
+// WithStrictSecretPermissions makes a client secret file that other users
+// can read an error (ErrInsecureSecretFile) instead of a warning.
+func WithStrictSecretPermissions() Option {
+	return func(s *settings) {
+		s.strictPerm = true
+	}
+}
+
+// WithScopes sets the OAuth2 scopes to request. A cached token whose grant
+// lacks any of them is replaced through the consent flow; see
+// WithIncrementalAuth.
+func WithScopes(scopes ...string) Option {
+	return func(s *settings) {
+		s.scopes = scopes
+	}
+}
+
+// WithTokenFile sets the key the token is cached under. With the default
+// store this is a file name inside the cache directory, see NewFileStore.
+func WithTokenFile(name string) Option {
+	return func(s *settings) {
+		s.tokenFile = name
+	}
+}
+
+// WithCacheDir sets the directory of the default FileStore. It has no
+// effect when a store is set with WithTokenStore.
+func WithCacheDir(dir string) Option {
+	return func(s *settings) {
+		s.cacheDir = dir
+	}
+}
+
+// WithTokenStore sets the store tokens are cached in. The default is a
+// FileStore in the directory NewFileStore picks, or localStorage when
+// running in a browser.
+func WithTokenStore(store TokenStore) Option {
+	return func(s *settings) {
+		s.store = store
+	}
+}
+
+// WithoutCache keeps tokens in process memory for the lifetime of the
+// Authenticator and never writes them to disk, so every run of the program
+// goes through the consent flow. It is shorthand for
+// WithTokenStore(&MemoryStore{}).
+func WithoutCache() Option {
+	return WithTokenStore(&MemoryStore{})
+}
+
+// WithPrompter sets how the steps of the consent flow are presented to the
+// user. The default is a TerminalPrompter.
+func WithPrompter(p Prompter) Option {
+	return func(s *settings) {
+		s.prompter = p
+	}
+}
+
+// WithoutInteraction forbids the consent flow. When no usable token is
+// cached the Authenticator fails with ErrConsentRequired instead of opening
+// a browser or reading from the terminal, which suits services and other
+// unattended programs.
+func WithoutInteraction() Option {
+	return func(s *settings) {
+		s.noPrompt = true
+	}
+}
+
+// WithMaxGrantAge forces a new consent once the user's grant is older than
+// d, for example 90 days, even though its refresh token still works. A
+// cached token whose grant time is unknown, such as one written by an
+// older version of this package, counts as too old.
+func WithMaxGrantAge(d time.Duration) Option {
+	return func(s *settings) {
+		s.maxAge = d
+	}
+}
+
+// WithFlow selects the consent flow used when no cached token is available.
+// The default is FlowAuto.
+func WithFlow(f Flow) Option {
+	return func(s *settings) {
+		s.flow = f
+	}
+}
+
+// WithCodeReceiver sets how the authorization code is obtained when no
+// cached token is available. It takes precedence over WithFlow.
+func WithCodeReceiver(r CodeReceiver) Option {
+	return func(s *settings) {
+		s.receiver = r
+	}
+}
+
+// WithAuthHandler hands the consent URL to h, which shows it to the user
+// its own way and returns the authorization code. It takes precedence over
+// WithFlow.
+func WithAuthHandler(h AuthCodeHandler) Option {
+	return WithCodeReceiver(&handlerReceiver{h: h})
+}
+
+// WithRedirectPort makes the loopback flow listen on port instead of a
+// free port picked at random, for clients whose redirect URI names one.
+func WithRedirectPort(port int) Option {
+	return func(s *settings) {
+		s.port = port
+	}
+}
+
+// WithBrowser controls whether the built-in flows open the consent page in
+// the system browser. With open false they only print its address, for
+// the user to open wherever they like. The default is true.
+func WithBrowser(open bool) Option {
+	return func(s *settings) {
+		s.noBrowser = !open
+	}
+}
+
+// WithFlowTimeout bounds the interactive consent flow, from presenting the
+// consent page to exchanging the code, to d. Zero means no limit beyond
+// the caller's context.
+func WithFlowTimeout(d time.Duration) Option {
+	return func(s *settings) {
+		s.timeout = d
+	}
+}
+
+// WithAuthCodeOptions adds parameters to the authorization URL, such as
+// oauth2.ApprovalForce or oauth2.SetAuthURLParam("login_hint", email).
+// They are not sent to the token endpoint; see WithExchangeOptions.
+// Repeated uses accumulate.
+func WithAuthCodeOptions(opts ...oauth2.AuthCodeOption) Option {
+	return func(s *settings) {
+		s.authOpts = append(s.authOpts, opts...)
+	}
+}
+
+// WithExchangeOptions adds parameters to the requests exchanging an
+// authorization or device code for a token. Repeated uses accumulate.
+func WithExchangeOptions(opts ...oauth2.AuthCodeOption) Option {
+	return func(s *settings) {
+		s.exchOpts = append(s.exchOpts, opts...)
+	}
+}
+
+// WithIncrementalAuth asks Google to include the scopes the user granted
+// the client before in new grants. When scopes are added to WithScopes,
+// the consent flow that runs again then only asks the user for the new
+// ones, and the new token still works for the old.
+func WithIncrementalAuth() Option {
+	return WithAuthCodeOptions(oauth2.SetAuthURLParam("include_granted_scopes", "true"))
+}
+
+// WithLogger sends diagnostic messages to l: which credentials are used,
+// cache hits and misses, each stage of the consent flow including the
+// browser launch, code exchanges, refreshes and failures to store or
+// refresh a token. By default nothing is logged, apart from warnings about
+// lost or dead grants, which go to standard error. Applications that need
+// the stages of the flow as values rather than text can use
+// WithEventHandler.
+func WithLogger(l Logger) Option {
+	return func(s *settings) {
+		s.logger = l
+	}
+}
@@ -0,0 +1,36 @@
+package googleauth
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"golang.org/x/oauth2"
+)
+
+// recoverPanic turns a panic in op, whether in googleauth or in a
+// user-supplied TokenStore, CodeReceiver or Prompter, into an ErrInternal
+// error. Panic values can hold tokens, so only the value's type is kept in
+// the error; the stack trace, which has no string contents, goes to the
+// Logger.
+func (a *Authenticator) recoverPanic(op string, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	a.logf("googleauth: recovered panic in %s:\n%s", op, debug.Stack())
+	*err = fmt.Errorf("%w: panic of type %T in %s", ErrInternal, r, op)
+}
+
+// storeGet reads the Authenticator's token from the store, treating a
+// store that returns neither a token nor an error as holding none.
+func (a *Authenticator) storeGet() (*oauth2.Token, error) {
+	tok, err := a.store.Get(a.key)
+	if err == nil && tok == nil {
+		err = ErrTokenNotCached
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return tok, nil
+}
@@ -0,0 +1,19 @@
+//go:build !android
+
+package googleauth
+
+import "github.com/godbus/dbus/v5"
+
+// openPortal opens url through the desktop portal, the only way a
+// confined app may launch the host's browser.
+func openPortal(url string) error {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return err
+	}
+	obj := conn.Object("org.freedesktop.portal.Desktop", "/org/freedesktop/portal/desktop")
+	call := obj.Call("org.freedesktop.portal.OpenURI.OpenURI", 0,
+		"", url, map[string]dbus.Variant{})
+
+	return call.Err
+}
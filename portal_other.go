@@ -0,0 +1,9 @@
+//go:build !linux || android
+
+package googleauth
+
+import "errors"
+
+func openPortal(url string) error {
+	return errors.New("googleauth: desktop portal not available")
+}
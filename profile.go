@@ -0,0 +1,116 @@
+package googleauth
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultProfile names the profile used without WithProfile. Its token is
+// cached under the plain key, as before profiles existed.
+const DefaultProfile = "default"
+
+// WithProfile caches the token under the named profile, so one program can
+// hold grants of several accounts, such as "work" and "personal", and pick
+// one per run. Each profile goes through the consent flow once. Names may
+// not contain a colon.
+func WithProfile(name string) Option {
+	return func(s *settings) {
+		if strings.Contains(name, ":") {
+			if s.err == nil {
+				s.err = fmt.Errorf("googleauth: invalid profile name %q", name)
+			}
+			return
+		}
+		s.profile = name
+	}
+}
+
+// profileKey returns the store key of profile's token for the key base.
+func profileKey(profile, base string) string {
+	if profile == "" || profile == DefaultProfile {
+		return base
+	}
+
+	return profile + ":" + base
+}
+
+// withKey returns an Authenticator like a whose token is cached under key.
+func (a *Authenticator) withKey(key string) *Authenticator {
+	return &Authenticator{
+		config:    a.config,
+		creds:     a.creds,
+		store:     a.store,
+		key:       key,
+		baseKey:   a.baseKey,
+		client:    a.client,
+		flow:      a.flow,
+		receiver:  a.receiver,
+		port:      a.port,
+		noBrowser: a.noBrowser,
+		prompter:  a.prompter,
+		noPrompt:  a.noPrompt,
+		timeout:   a.timeout,
+		retry:     a.retry,
+		authOpts:  a.authOpts,
+		exchOpts:  a.exchOpts,
+		maxAge:    a.maxAge,
+		fips:      a.fips,
+		issuer:    a.issuer,
+		revokeURL: a.revokeURL,
+		msgs:      a.msgs,
+		hc:        a.hc,
+		logger:    a.logger,
+		events:    a.events,
+		telemetry: a.telemetry,
+
+		refreshWindow: a.refreshWindow,
+		refreshJitter: a.refreshJitter,
+	}
+}
+
+// ListProfiles returns the names of the profiles with a cached token for
+// the Authenticator's client and token file, sorted. The store must
+// implement TokenLister.
+func (a *Authenticator) ListProfiles() ([]string, error) {
+	l, ok := a.store.(TokenLister)
+	if !ok {
+		return nil, ErrNotListable
+	}
+	keys, err := l.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, k := range keys {
+		switch {
+		case k == a.baseKey:
+			names = append(names, DefaultProfile)
+		case strings.HasSuffix(k, ":"+a.baseKey):
+			names = append(names, strings.TrimSuffix(k, ":"+a.baseKey))
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// DeleteProfile deletes the cached token of the named profile, and its
+// backup, without revoking it. Deleting a profile without a token is not
+// an error.
+func (a *Authenticator) DeleteProfile(name string) error {
+	key := profileKey(name, a.baseKey)
+	if err := a.store.Delete(key); err != nil {
+		return err
+	}
+	if err := a.store.Delete(previousKey(key)); err != nil {
+		return err
+	}
+	if key == a.key {
+		a.setToken(nil)
+	}
+	a.logf("googleauth: deleted profile %q", name)
+
+	return nil
+}
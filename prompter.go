@@ -0,0 +1,61 @@
+package googleauth
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// A Prompter presents the steps of the consent flow to the user. The
+// built-in flows report to it instead of printing, so desktop applications
+// can show the consent URL, progress and errors in their own dialogs.
+// Methods are called from the goroutine running the flow.
+type Prompter interface {
+	// ShowConsentURL asks the user to open the consent page. opened
+	// reports whether the system browser was already pointed at it.
+	ShowConsentURL(authURL string, opened bool)
+	// ShowDeviceCode asks the user to visit verificationURL on any device
+	// and enter userCode before expiry.
+	ShowDeviceCode(verificationURL, userCode string, expiry time.Time)
+	// ShowProgress reports a step of the flow, such as waiting for the
+	// user to approve.
+	ShowProgress(msg string)
+	// ShowResult reports the end of the flow. err is nil on success.
+	ShowResult(err error)
+}
+
+// TerminalPrompter is the default Prompter. It prints only what the user
+// must act on.
+type TerminalPrompter struct {
+	// Out is where instructions are written. Nil means os.Stdout.
+	Out io.Writer
+	// Messages are the texts shown. Nil means the environment's language.
+	Messages *Messages
+}
+
+func (p *TerminalPrompter) out() io.Writer {
+	if p.Out == nil {
+		return os.Stdout
+	}
+
+	return p.Out
+}
+
+// ShowConsentURL implements Prompter.
+func (p *TerminalPrompter) ShowConsentURL(authURL string, opened bool) {
+	if !opened {
+		fmt.Fprintf(p.out(), "%s \n%v\n", messagesOr(p.Messages).OpenLink, authURL)
+	}
+}
+
+// ShowDeviceCode implements Prompter.
+func (p *TerminalPrompter) ShowDeviceCode(verificationURL, userCode string, expiry time.Time) {
+	fmt.Fprintf(p.out(), messagesOr(p.Messages).EnterDeviceCode+" \n%v\n", userCode, verificationURL)
+}
+
+// ShowProgress implements Prompter.
+func (p *TerminalPrompter) ShowProgress(msg string) {}
+
+// ShowResult implements Prompter.
+func (p *TerminalPrompter) ShowResult(err error) {}
@@ -0,0 +1,57 @@
+package googleauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// A PushVerifier authenticates requests that Pub/Sub push subscriptions
+// and Cloud Scheduler HTTP jobs send with an OIDC token.
+type PushVerifier struct {
+	// Audience is the audience configured on the subscription or job,
+	// by default the push endpoint URL.
+	Audience string
+	// Email is the service account the subscription or job is set to
+	// authenticate as. Empty accepts any.
+	Email string
+}
+
+var errNoBearer = errors.New("no bearer token")
+
+// Verify checks the bearer token of r and returns its claims.
+func (v *PushVerifier) Verify(r *http.Request) (*IDTokenClaims, error) {
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, "Bearer ") {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, errNoBearer)
+	}
+
+	return v.VerifyToken(r.Context(), strings.TrimPrefix(h, "Bearer "))
+}
+
+// VerifyToken checks the OIDC token raw and returns its claims.
+func (v *PushVerifier) VerifyToken(ctx context.Context, raw string) (*IDTokenClaims, error) {
+	c, err := (&IDTokenVerifier{Audience: v.Audience}).Verify(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+	if v.Email != "" && (c.Email != v.Email || !c.EmailVerified) {
+		return nil, fmt.Errorf("%w: unexpected email %q", ErrInvalidIDToken, c.Email)
+	}
+
+	return c, nil
+}
+
+// Handler returns a handler that serves only requests passing Verify with
+// next, answering the rest with 401 Unauthorized.
+func (v *PushVerifier) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := v.Verify(r); err != nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,177 @@
+package googleauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+
+	"golang.org/x/oauth2"
+)
+
+// An AuthRequest describes a pending authorization code request handed to a
+// CodeReceiver.
+type AuthRequest struct {
+	// Config is a copy of the client configuration. A receiver that serves
+	// the redirect itself sets Config.RedirectURL before calling
+	// AuthCodeURL; the same Config is then used to exchange the code.
+	Config *oauth2.Config
+	// State is the value the authorization server echoes back with the
+	// code. It is random for each request; receivers that see the redirect
+	// must reject one whose state differs.
+	State string
+	// Options are added to the authorization URL.
+	Options []oauth2.AuthCodeOption
+}
+
+// AuthCodeURL returns the consent page URL for the request.
+func (r *AuthRequest) AuthCodeURL() string {
+	return r.Config.AuthCodeURL(r.State, r.Options...)
+}
+
+// A CodeReceiver presents the consent page to the user and returns the
+// authorization code Google issues once they approve.
+type CodeReceiver interface {
+	ReceiveCode(ctx context.Context, req *AuthRequest) (string, error)
+}
+
+// CodeReceiverFunc adapts an ordinary function to a CodeReceiver. It is the
+// simplest way to hand the consent URL to a custom channel (a chat bot, a
+// push notification) and wait for the code to come back.
+type CodeReceiverFunc func(ctx context.Context, req *AuthRequest) (string, error)
+
+// ReceiveCode calls f(ctx, req).
+func (f CodeReceiverFunc) ReceiveCode(ctx context.Context, req *AuthRequest) (string, error) {
+	return f(ctx, req)
+}
+
+// An AuthCodeHandler presents the consent page at authURL to the user and
+// returns the authorization code, or the address of the page the browser
+// was redirected to, which holds it. It is a simpler CodeReceiver for GUI
+// apps, TUIs and web backends with a consent UX of their own. The browser
+// is redirected to the first redirect URI of the client secret.
+type AuthCodeHandler func(ctx context.Context, authURL string) (string, error)
+
+// TerminalAuthCodeHandler is the AuthCodeHandler behind the paste flow: it
+// opens the consent page in the system browser, or prints its address,
+// and reads what the user pastes from standard input.
+func TerminalAuthCodeHandler(ctx context.Context, authURL string) (string, error) {
+	return (&PasteReceiver{}).readCode(ctx, authURL)
+}
+
+// handlerReceiver is the CodeReceiver of WithAuthHandler.
+type handlerReceiver struct {
+	h AuthCodeHandler
+}
+
+func (r *handlerReceiver) ReceiveCode(ctx context.Context, req *AuthRequest) (string, error) {
+	s, err := r.h(ctx, req.AuthCodeURL())
+	if err != nil {
+		return "", err
+	}
+
+	return codeFromInput(s, req.State)
+}
+
+type codeResult struct {
+	code string
+	err  error
+}
+
+// PasteReceiver opens the consent page in a browser and reads the code the
+// user copies from it. When the redirect goes to a loopback address nobody
+// is listening on, the user may instead paste the address of the page the
+// browser ended up on and the code is taken from it.
+type PasteReceiver struct {
+	// In is where the code is read from. Nil means os.Stdin.
+	In io.Reader
+	// Out is where instructions are written when there is no Prompter.
+	// Nil means os.Stdout.
+	Out io.Writer
+	// Prompter is told about the consent page and the wait for the code.
+	// Nil means the instructions are written to Out.
+	Prompter Prompter
+	// Messages are the texts shown. Nil means the environment's language.
+	Messages *Messages
+
+	shown     func(opened bool) // reports the consent page, if set
+	noBrowser bool              // only print the consent page address
+}
+
+// ReceiveCode implements CodeReceiver. If ctx is done before a code is
+// read, ReceiveCode returns ctx.Err() and abandons the pending read.
+func (p *PasteReceiver) ReceiveCode(ctx context.Context, req *AuthRequest) (string, error) {
+	s, err := p.readCode(ctx, req.AuthCodeURL())
+	if err != nil {
+		return "", err
+	}
+
+	return codeFromInput(s, req.State)
+}
+
+// readCode presents authURL and returns what the user pastes.
+func (p *PasteReceiver) readCode(ctx context.Context, authURL string) (string, error) {
+	in, out := p.In, p.Out
+	if in == nil {
+		in = os.Stdin
+	}
+	if out == nil {
+		out = os.Stdout
+	}
+
+	msgs := messagesOr(p.Messages)
+	if p.Prompter == nil {
+		fmt.Fprintln(out, msgs.TypeCode+" ")
+	}
+	opened := !p.noBrowser && openBrowser(authURL) == nil
+	if p.Prompter != nil {
+		p.Prompter.ShowConsentURL(authURL, opened)
+		p.Prompter.ShowProgress(msgs.TypeCode)
+	} else if !opened {
+		fmt.Fprintf(out, "%s \n%v\n", msgs.OpenLinkTypeCode, authURL)
+	}
+	if p.shown != nil {
+		p.shown(opened)
+	}
+
+	results := make(chan codeResult, 1)
+	go func() {
+		var res codeResult
+		_, res.err = fmt.Fscan(in, &res.code)
+		results <- res
+	}()
+
+	select {
+	case res := <-results:
+		return res.code, res.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// codeFromInput returns the code query parameter if s is a redirect URL,
+// after checking its state, and s itself otherwise. A redirect reporting
+// that the user denied consent, or any other error, is an error.
+func codeFromInput(s, state string) (string, error) {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" {
+		return s, nil
+	}
+	q := u.Query()
+	if e := q.Get("error"); e != "" {
+		if d := q.Get("error_description"); d != "" {
+			e += ": " + d
+		}
+		return "", fmt.Errorf("googleauth: authorization failed: %s", e)
+	}
+	if q.Get("code") == "" {
+		return "", errors.New("googleauth: redirect has no authorization code")
+	}
+	if q.Get("state") != state {
+		return "", errors.New("googleauth: redirect state does not match")
+	}
+
+	return q.Get("code"), nil
+}
@@ -0,0 +1,90 @@
+package googleauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCodeFromInput(t *testing.T) {
+	tests := []struct {
+		in      string
+		code    string
+		wantErr string
+	}{
+		{in: "4/plain-code", code: "4/plain-code"},
+		{in: "http://localhost/?state=s&code=c", code: "c"},
+		{in: "http://localhost/?state=other&code=c", wantErr: "state does not match"},
+		{in: "http://localhost/?state=s&error=access_denied&error_description=denied+by+user", wantErr: "access_denied: denied by user"},
+		{in: "http://localhost/?state=s", wantErr: "no authorization code"},
+	}
+	for _, tt := range tests {
+		code, err := codeFromInput(tt.in, "s")
+		switch {
+		case tt.wantErr == "" && (err != nil || code != tt.code):
+			t.Errorf("codeFromInput(%q) = %q, %v, want %q", tt.in, code, err, tt.code)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("codeFromInput(%q) = %q, %v, want an error with %q", tt.in, code, err, tt.wantErr)
+		}
+	}
+}
+
+// pastePrompter pastes the redirect a user would from the consent URL it
+// is shown.
+type pastePrompter struct {
+	w io.WriteCloser
+
+	challenge string
+}
+
+func (p *pastePrompter) ShowConsentURL(authURL string, opened bool) {
+	u, _ := url.Parse(authURL)
+	q := u.Query()
+	p.challenge = q.Get("code_challenge")
+	// The receiver reads only once it has shown the URL.
+	go fmt.Fprintf(p.w, "http://localhost/?state=%s&code=pasted-code\n", url.QueryEscape(q.Get("state")))
+}
+func (p *pastePrompter) ShowDeviceCode(string, string, time.Time) {}
+func (p *pastePrompter) ShowProgress(string)                      {}
+func (p *pastePrompter) ShowResult(error)                         {}
+
+func TestPasteFlowPKCE(t *testing.T) {
+	var code, verifier string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		code, verifier = r.PostForm.Get("code"), r.PostForm.Get("code_verifier")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"at","refresh_token":"rt","token_type":"Bearer","expires_in":3600}`)
+	}))
+	defer srv.Close()
+	secret := fmt.Sprintf(`{"installed":{"client_id":"id","client_secret":"secret",`+
+		`"redirect_uris":["http://localhost"],"token_uri":%q}}`, srv.URL)
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	p := &pastePrompter{w: pw}
+	recv := &PasteReceiver{In: pr, Prompter: p, noBrowser: true}
+	a, err := NewAuthenticator(context.Background(), WithSecret([]byte(secret)), WithCodeReceiver(recv), WithoutCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if code != "pasted-code" {
+		t.Fatalf("exchanged code %q, want pasted-code", code)
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	if p.challenge == "" || base64.RawURLEncoding.EncodeToString(sum[:]) != p.challenge {
+		t.Fatalf("code_verifier %q does not match the S256 challenge %q", verifier, p.challenge)
+	}
+}
@@ -0,0 +1,124 @@
+package googleauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// refreshingSource returns the Authenticator's current token, refreshing
+// it through the Authenticator once it has expired, so every TokenSource
+// and Client created from one Authenticator shares a single token.
+type refreshingSource struct {
+	a   *Authenticator
+	ctx context.Context
+}
+
+func (s *refreshingSource) Token() (_ *oauth2.Token, err error) {
+	defer s.a.recoverPanic("Token", &err)
+
+	tok := s.a.memToken()
+	if tok == nil {
+		// The dead grant was dropped; pick up a new one if the user has
+		// authorized again elsewhere.
+		tok, err = s.a.storeGet()
+		if err != nil {
+			return nil, ErrReauthorizationRequired
+		}
+		s.a.setToken(tok)
+	}
+	if s.a.grantTooOld(tok) {
+		// Forget it, so that the next Authenticator.Token runs the
+		// consent flow; this source picks up the new grant from the store.
+		s.a.setToken(nil)
+		return nil, fmt.Errorf("%w: %v", ErrConsentRequired, errGrantTooOld)
+	}
+	if s.a.fresh(tok) {
+		return tok, nil
+	}
+
+	return s.a.refresh(s.ctx, false)
+}
+
+// refresh exchanges the refresh token for a new access token.
+//
+// Every refreshed token is stored, so other processes and later runs
+// start from it. Google may rotate the refresh token on use, invalidating
+// the old one, so refreshes are serialized and a rotated refresh token is
+// stored before anyone else can refresh. The store is consulted first in
+// case another process has already refreshed or rotated the token. Unless
+// force is set, a token that is not yet due for refresh, or no longer is
+// after waiting for another refresh, is returned as is.
+func (a *Authenticator) refresh(ctx context.Context, force bool) (*oauth2.Token, error) {
+	a.refreshMu.Lock()
+	defer a.refreshMu.Unlock()
+
+	old := a.memToken()
+	if stored, err := a.storeGet(); err == nil {
+		if old == nil || stored.RefreshToken != old.RefreshToken || stored.Expiry.After(old.Expiry) {
+			old = stored
+		}
+	}
+	if old == nil {
+		return nil, ErrTokenNotCached
+	}
+	if !force && a.fresh(old) {
+		a.setToken(old)
+		return old, nil
+	}
+	if old.RefreshToken == "" {
+		return nil, ErrTokenExpired
+	}
+
+	// The oauth2 package keeps the old refresh token when the response
+	// carries none, so a different one means Google rotated it.
+	start := time.Now()
+	tok, err := a.withRetry(ctx, "refresh", isTransient, func() (*oauth2.Token, error) {
+		return a.config.TokenSource(ctx, &oauth2.Token{RefreshToken: old.RefreshToken}).Token()
+	})
+	err = exchangeError("refresh", err)
+	a.report("refresh", "", start, err)
+	if err != nil {
+		a.logf("googleauth: refreshing token for %q failed: %v", a.key, err)
+	}
+	if errors.Is(err, ErrReauthorizationRequired) {
+		a.dropGrant(old)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	tok = withExtra(tok, nil, old)
+	a.setToken(tok)
+	a.logf("googleauth: refreshed token for %q, valid until %s", a.key, tok.Expiry.Format(time.RFC3339))
+	if tok.RefreshToken != old.RefreshToken {
+		a.logf("googleauth: refresh token for %q rotated", a.key)
+		if err := a.putToken(tok); err != nil {
+			// The new refresh token now only lives in memory; the stored
+			// one no longer works.
+			a.warnf("googleauth: WARNING: cannot store rotated refresh token for %q: %v", a.key, err)
+		}
+	} else if err := a.store.Put(a.key, tok); err != nil {
+		// Only the access token is lost; the stored grant still works.
+		// The backup is left alone, as it is the previous grant.
+		a.logf("googleauth: cannot store refreshed token for %q: %v", a.key, err)
+	}
+
+	return tok, nil
+}
+
+// dropGrant forgets the dead token old, removing it from the store unless
+// the store meanwhile holds a different grant.
+func (a *Authenticator) dropGrant(old *oauth2.Token) {
+	a.setToken(nil)
+	a.warnf("googleauth: WARNING: refresh token for %q was revoked or has expired; authorize again", a.key)
+	if stored, err := a.storeGet(); err != nil || stored.RefreshToken != old.RefreshToken {
+		return
+	}
+	if err := a.store.Delete(a.key); err != nil {
+		a.warnf("googleauth: WARNING: cannot delete dead token for %q: %v", a.key, err)
+	}
+}
@@ -0,0 +1,137 @@
+package googleauth_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jarodmeng/googleauth/v2"
+	"github.com/jarodmeng/googleauth/v2/googleauthtest"
+	"golang.org/x/oauth2"
+)
+
+const tokenFile = "token.json"
+
+func newAuthenticator(t *testing.T, srv *googleauthtest.Server, store googleauth.TokenStore) *googleauth.Authenticator {
+	a, err := googleauth.NewAuthenticator(context.Background(),
+		googleauth.WithSecret(srv.Secret()),
+		googleauth.WithHTTPClient(srv.HTTPClient()),
+		googleauth.WithAuthHandler(srv.AuthCodeHandler()),
+		googleauth.WithTokenStore(store),
+		googleauth.WithTokenFile(tokenFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a
+}
+
+// expireStored makes the token in store due for refresh.
+func expireStored(t *testing.T, store googleauth.TokenStore) {
+	tok, err := store.Get(tokenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok.Expiry = time.Now().Add(-time.Minute)
+	if err := store.Put(tokenFile, tok); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRefreshRotation(t *testing.T) {
+	ctx := context.Background()
+	srv := googleauthtest.NewServer()
+	defer srv.Close()
+	srv.SetRefreshTokenRotation(true)
+	store := &googleauth.MemoryStore{}
+	a := newAuthenticator(t, srv, store)
+	first, err := a.Token(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tok, err := a.RefreshNow(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.RefreshToken == first.RefreshToken {
+		t.Fatal("refresh token not rotated")
+	}
+	stored, err := store.Get(tokenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.RefreshToken != tok.RefreshToken {
+		t.Fatalf("stored refresh token %q, want the rotated %q", stored.RefreshToken, tok.RefreshToken)
+	}
+
+	// A later run starts from the rotated token.
+	if _, err := newAuthenticator(t, srv, store).RefreshNow(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Exchanges(); n != 1 {
+		t.Fatalf("%d code exchanges, want 1", n)
+	}
+}
+
+func TestRefreshConcurrent(t *testing.T) {
+	ctx := context.Background()
+	srv := googleauthtest.NewServer()
+	defer srv.Close()
+	srv.SetRefreshTokenRotation(true)
+	store := &googleauth.MemoryStore{}
+	if _, err := newAuthenticator(t, srv, store).Token(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expireStored(t, store)
+
+	a := newAuthenticator(t, srv, store)
+	ts, err := a.TokenSource(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	toks := make([]*oauth2.Token, 8)
+	errs := make([]error, len(toks))
+	var wg sync.WaitGroup
+	for i := range toks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			toks[i], errs[i] = ts.Token()
+		}(i)
+	}
+	wg.Wait()
+	for i := range toks {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if toks[i].RefreshToken != toks[0].RefreshToken {
+			t.Fatalf("token %d has refresh token %q, token 0 %q", i, toks[i].RefreshToken, toks[0].RefreshToken)
+		}
+	}
+	if n := srv.Refreshes(); n != 1 {
+		t.Fatalf("%d refreshes, want 1", n)
+	}
+}
+
+func TestRefreshInvalidGrant(t *testing.T) {
+	ctx := context.Background()
+	srv := googleauthtest.NewServer()
+	defer srv.Close()
+	store := &googleauth.MemoryStore{}
+	if _, err := newAuthenticator(t, srv, store).Token(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expireStored(t, store)
+	srv.RevokeAll()
+
+	_, err := newAuthenticator(t, srv, store).Token(ctx)
+	if !errors.Is(err, googleauth.ErrReauthorizationRequired) {
+		t.Fatalf("Token = %v, want ErrReauthorizationRequired", err)
+	}
+	if _, err := store.Get(tokenFile); !errors.Is(err, googleauth.ErrTokenNotCached) {
+		t.Fatalf("dead grant still stored: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package googleauth
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// A RetryPolicy controls how the code exchange and refreshes are retried
+// after transient failures: network errors and 429 or 5xx responses from
+// the token endpoint. The single-use code exchange is retried after a
+// network error only if the connection was never made. Rejections such as
+// invalid_grant are never retried.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts, including the first. One or
+	// less disables retries.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry. It is multiplied
+	// by Multiplier for every further retry, up to MaxBackoff. Each wait is
+	// shortened by a random amount of up to half, so that many clients
+	// failing together do not retry together.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+}
+
+// DefaultRetryPolicy is used unless WithRetryPolicy sets another.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	Multiplier:     2,
+}
+
+// WithRetryPolicy sets how the code exchange and refreshes are retried.
+// RetryPolicy{} disables retries.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(s *settings) {
+		s.retry = &p
+	}
+}
+
+// backoff returns the wait before retry n, counting from zero.
+func (p *RetryPolicy) backoff(n int) time.Duration {
+	d := float64(p.InitialBackoff)
+	for i := 0; i < n; i++ {
+		d *= p.Multiplier
+	}
+	if max := float64(p.MaxBackoff); max > 0 && d > max {
+		d = max
+	}
+
+	return time.Duration(d/2 + rand.Float64()*d/2)
+}
+
+// withRetry calls fn until it succeeds, fails with an error transient
+// does not accept or the policy's attempts are used up.
+func (a *Authenticator) withRetry(ctx context.Context, op string, transient func(error) bool, fn func() (*oauth2.Token, error)) (*oauth2.Token, error) {
+	for n := 0; ; n++ {
+		tok, err := fn()
+		if err == nil || n+1 >= a.retry.MaxAttempts || !transient(err) {
+			return tok, err
+		}
+		d := a.retry.backoff(n)
+		a.logf("googleauth: %s failed, retrying in %v: %v", op, d, err)
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+			return nil, err
+		}
+	}
+}
+
+// isTransient reports whether err may go away when the request is made
+// again.
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var re *oauth2.RetrieveError
+	if errors.As(err, &re) {
+		return isTransientStatus(re)
+	}
+	var ne net.Error
+
+	return errors.As(err, &ne)
+}
+
+// isTransientExchange is isTransient for the code exchange. A code is
+// single-use, and sending one again may revoke the tokens already issued
+// for it (RFC 6749, section 4.1.2), so only 429 and 5xx responses and
+// connections that were never made are retried, not failures after the
+// request may have reached the endpoint.
+func isTransientExchange(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var re *oauth2.RetrieveError
+	if errors.As(err, &re) {
+		return isTransientStatus(re)
+	}
+	var oe *net.OpError
+
+	return errors.As(err, &oe) && oe.Op == "dial"
+}
+
+func isTransientStatus(re *oauth2.RetrieveError) bool {
+	if re.Response == nil {
+		return false
+	}
+	code := re.Response.StatusCode
+
+	return code == http.StatusTooManyRequests || code >= 500
+}
@@ -0,0 +1,194 @@
+package googleauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+const googleRevokeURL = "https://oauth2.googleapis.com/revoke"
+
+// RefreshNow exchanges the cached refresh token for a new access token
+// regardless of whether the current one has expired, and stores the
+// result. It fails with ErrTokenNotCached if there is no cached token and
+// with ErrConsentRequired if the token cannot be refreshed, or
+// ErrReauthorizationRequired if Google no longer accepts it.
+func (a *Authenticator) RefreshNow(ctx context.Context) (_ *oauth2.Token, err error) {
+	defer a.recoverPanic("RefreshNow", &err)
+
+	if a.config == nil {
+		return nil, errNoOAuthClient
+	}
+
+	return a.refresh(a.withHTTPClient(ctx), true)
+}
+
+// Revoke revokes the cached grant with Google, or the WithIssuer
+// provider, and deletes it, and its backup, from the store. Revoking when
+// no token is cached is not an error.
+func (a *Authenticator) Revoke(ctx context.Context) (err error) {
+	defer a.recoverPanic("Revoke", &err)
+
+	if a.config == nil {
+		return errNoOAuthClient
+	}
+	if a.revokeURL == "" {
+		return fmt.Errorf("googleauth: issuer %s publishes no revocation endpoint", a.issuer)
+	}
+	tok, err := a.storeGet()
+	if errors.Is(err, ErrTokenNotCached) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = revokeGrant(a.withHTTPClient(ctx), a.revokeURL, tok)
+	if err != nil {
+		return err
+	}
+	a.setToken(nil)
+	if err := a.store.Delete(previousKey(a.key)); err != nil {
+		return err
+	}
+
+	return a.store.Delete(a.key)
+}
+
+// RevokeToken revokes the grant cached under tokenFile with Google and
+// deletes it. No client secret is needed. The token is looked up in the
+// default cache directory, as used by CreateClient, unless opts say
+// otherwise: WithTokenStore, WithCacheDir, WithCacheKey, WithProfile and
+// WithHTTPClient are honored, other options ignored. Revoking when no
+// token is cached is not an error.
+func RevokeToken(ctx context.Context, tokenFile string, opts ...Option) error {
+	ctx, store, key, err := cachedTokenOpts(ctx, tokenFile, opts)
+	if err != nil {
+		return err
+	}
+	tok, err := store.Get(key)
+	if errors.Is(err, ErrTokenNotCached) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := revokeGrant(ctx, googleRevokeURL, tok); err != nil {
+		return err
+	}
+	if err := store.Delete(previousKey(key)); err != nil {
+		return err
+	}
+
+	return store.Delete(key)
+}
+
+// cachedTokenOpts applies the options of RevokeToken and TokenInfo. It
+// returns ctx carrying their HTTP client, the store and the key of the
+// token cached under tokenFile.
+func cachedTokenOpts(ctx context.Context, tokenFile string, opts []Option) (context.Context, TokenStore, string, error) {
+	s := newSettings(opts)
+	if s.err != nil {
+		return nil, nil, "", s.err
+	}
+	store, err := s.tokenStore()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	hc, _, err := newHTTPClient(s)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	a := &Authenticator{hc: hc}
+
+	return a.withHTTPClient(ctx), store, profileKey(s.profile, tokenFile), nil
+}
+
+// ClearCache deletes every token cached in dir, or in the default cache
+// directory if dir is empty, and their backups, without revoking them.
+// Every program sharing the directory has to go through the consent flow
+// again.
+func ClearCache(dir string) error {
+	store, err := NewFileStore(dir)
+	if err != nil {
+		return err
+	}
+	keys, err := store.Keys()
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err := store.Delete(previousKey(k)); err != nil {
+			return err
+		}
+		if err := store.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// revokeGrant revokes tok's refresh token at endpoint, which also
+// invalidates the access tokens issued from it, and then the access token
+// itself in case it was issued otherwise. Only the first revocation must
+// succeed.
+func revokeGrant(ctx context.Context, endpoint string, tok *oauth2.Token) error {
+	if tok.RefreshToken == "" {
+		return revokeToken(ctx, endpoint, tok.AccessToken)
+	}
+	if err := revokeToken(ctx, endpoint, tok.RefreshToken); err != nil {
+		return err
+	}
+	if tok.AccessToken != "" {
+		revokeToken(ctx, endpoint, tok.AccessToken)
+	}
+
+	return nil
+}
+
+func revokeToken(ctx context.Context, endpoint, token string) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint,
+		strings.NewReader(url.Values{"token": {token}}.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := contextClient(ctx).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusBadRequest {
+		var j struct {
+			Error string `json:"error"`
+		}
+		// A token Google already revoked or let expire is as good as
+		// revoked, and must still be deleted locally.
+		if json.NewDecoder(resp.Body).Decode(&j) == nil && j.Error == "invalid_token" {
+			return nil
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("googleauth: revoke: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// contextClient returns the HTTP client set on ctx with oauth2.HTTPClient,
+// the same way the oauth2 package picks one.
+func contextClient(ctx context.Context) *http.Client {
+	if c, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok {
+		return c
+	}
+
+	return http.DefaultClient
+}
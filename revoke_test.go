@@ -0,0 +1,68 @@
+package googleauth_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jarodmeng/googleauth/v2"
+	"github.com/jarodmeng/googleauth/v2/googleauthtest"
+)
+
+func TestRevokeTokenOptions(t *testing.T) {
+	ctx := context.Background()
+	srv := googleauthtest.NewServer()
+	defer srv.Close()
+	opts := []googleauth.Option{
+		googleauth.WithCacheDir(t.TempDir()),
+		googleauth.WithCacheKey(bytes.Repeat([]byte{7}, 32)),
+		googleauth.WithProfile("work"),
+		googleauth.WithHTTPClient(srv.HTTPClient()),
+	}
+	a, err := googleauth.NewAuthenticator(ctx, append(opts,
+		googleauth.WithSecret(srv.Secret()),
+		googleauth.WithAuthHandler(srv.AuthCodeHandler()),
+		googleauth.WithTokenFile("t.json"),
+		googleauth.WithScopes("email"))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Token(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	ct, err := googleauth.TokenInfo(ctx, "t.json", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ct.HasRefreshToken {
+		t.Fatal("TokenInfo: no refresh token")
+	}
+	if err := googleauth.RevokeToken(ctx, "t.json", opts...); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := googleauth.TokenInfo(ctx, "t.json", opts...); !errors.Is(err, googleauth.ErrTokenNotCached) {
+		t.Fatalf("TokenInfo after RevokeToken = %v, want ErrTokenNotCached", err)
+	}
+}
+
+func TestRevokeTokenRevokedUpstream(t *testing.T) {
+	ctx := context.Background()
+	srv := googleauthtest.NewServer()
+	defer srv.Close()
+	store := &googleauth.MemoryStore{}
+	a := newAuthenticator(t, srv, store)
+	if _, err := a.Token(ctx); err != nil {
+		t.Fatal(err)
+	}
+	srv.RevokeAll()
+
+	opts := []googleauth.Option{googleauth.WithTokenStore(store), googleauth.WithHTTPClient(srv.HTTPClient())}
+	if err := googleauth.RevokeToken(ctx, tokenFile, opts...); err != nil {
+		t.Fatalf("RevokeToken of a grant revoked upstream = %v", err)
+	}
+	if _, err := store.Get(tokenFile); !errors.Is(err, googleauth.ErrTokenNotCached) {
+		t.Fatalf("token still cached: %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package googleauth
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// RichPrompter is a Prompter for interactive terminals. It shows a spinner
+// while waiting for the user, counts down until a device code expires and
+// reports the result in color. When Out isn't a terminal it prints plain
+// lines instead, and it leaves out colors when NO_COLOR is set. Use it with
+// WithPrompter.
+type RichPrompter struct {
+	// Out is where output is written. Nil means os.Stderr.
+	Out io.Writer
+	// Messages are the texts shown. Nil means the environment's language.
+	Messages *Messages
+
+	mu     sync.Mutex
+	expiry time.Time     // of the device code, if any
+	stop   chan struct{} // closes to stop the spinner
+	done   chan struct{} // closed once the spinner has stopped
+}
+
+func (p *RichPrompter) out() io.Writer {
+	if p.Out == nil {
+		return os.Stderr
+	}
+
+	return p.Out
+}
+
+func (p *RichPrompter) isTerminal() bool {
+	f, ok := p.out().(interface{ Fd() uintptr })
+
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+func (p *RichPrompter) color(code, s string) string {
+	if os.Getenv("NO_COLOR") != "" {
+		return s
+	}
+
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+// ShowConsentURL implements Prompter.
+func (p *RichPrompter) ShowConsentURL(authURL string, opened bool) {
+	if !opened {
+		p.stopSpinner()
+		fmt.Fprintf(p.out(), "%s \n%v\n", messagesOr(p.Messages).OpenLink, authURL)
+	}
+}
+
+// ShowDeviceCode implements Prompter.
+func (p *RichPrompter) ShowDeviceCode(verificationURL, userCode string, expiry time.Time) {
+	p.stopSpinner()
+	code := userCode
+	if p.isTerminal() {
+		code = p.color("1", userCode)
+	}
+	fmt.Fprintf(p.out(), messagesOr(p.Messages).EnterDeviceCode+" \n%v\n", code, verificationURL)
+	p.mu.Lock()
+	p.expiry = expiry
+	p.mu.Unlock()
+}
+
+// ShowProgress implements Prompter.
+func (p *RichPrompter) ShowProgress(msg string) {
+	p.stopSpinner()
+	if !p.isTerminal() {
+		fmt.Fprintln(p.out(), msg)
+		return
+	}
+
+	p.mu.Lock()
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	stop, done, expiry := p.stop, p.done, p.expiry
+	p.mu.Unlock()
+
+	msgs := messagesOr(p.Messages)
+	go func() {
+		defer close(done)
+		t := time.NewTicker(100 * time.Millisecond)
+		defer t.Stop()
+		for i := 0; ; i++ {
+			line := spinnerFrames[i%len(spinnerFrames)] + " " + msg
+			if !expiry.IsZero() {
+				left := time.Until(expiry).Round(time.Second)
+				if left < 0 {
+					left = 0
+				}
+				line += " (" + fmt.Sprintf(msgs.ExpiresIn, left) + ")"
+			}
+			fmt.Fprintf(p.out(), "\r%s\x1b[K", line)
+			select {
+			case <-stop:
+				fmt.Fprint(p.out(), "\r\x1b[K")
+				return
+			case <-t.C:
+			}
+		}
+	}()
+}
+
+// ShowResult implements Prompter.
+func (p *RichPrompter) ShowResult(err error) {
+	p.stopSpinner()
+	p.mu.Lock()
+	p.expiry = time.Time{}
+	p.mu.Unlock()
+
+	msgs := messagesOr(p.Messages)
+	tty := p.isTerminal()
+	if err != nil {
+		s := fmt.Sprintf(msgs.AuthorizationFailed, err)
+		if tty {
+			s = p.color("31", "✗ "+s)
+		}
+		fmt.Fprintln(p.out(), s)
+		return
+	}
+	s := msgs.Authorized
+	if tty {
+		s = p.color("32", "✓ "+s)
+	}
+	fmt.Fprintln(p.out(), s)
+}
+
+func (p *RichPrompter) stopSpinner() {
+	p.mu.Lock()
+	stop, done := p.stop, p.done
+	p.stop, p.done = nil, nil
+	p.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
@@ -0,0 +1,39 @@
+package googleauth
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// inSnap reports whether the program runs confined as a snap.
+func inSnap() bool {
+	return os.Getenv("SNAP") != "" && os.Getenv("SNAP_NAME") != ""
+}
+
+// inFlatpak reports whether the program runs inside a Flatpak sandbox.
+func inFlatpak() bool {
+	if os.Getenv("FLATPAK_ID") != "" {
+		return true
+	}
+	_, err := os.Stat("/.flatpak-info")
+	return err == nil
+}
+
+// sandboxCacheDir returns the cache directory for a confined program, or
+// "" when not confined. Snaps use SNAP_USER_COMMON so tokens survive snap
+// refreshes; Flatpak redirects XDG_CONFIG_HOME into the app's own
+// directory.
+func sandboxCacheDir() string {
+	if inSnap() {
+		if dir := os.Getenv("SNAP_USER_COMMON"); dir != "" {
+			return filepath.Join(dir, ".credentials")
+		}
+	}
+	if inFlatpak() {
+		if dir, err := os.UserConfigDir(); err == nil {
+			return filepath.Join(dir, "googleauth")
+		}
+	}
+
+	return ""
+}
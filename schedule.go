@@ -0,0 +1,80 @@
+package googleauth
+
+import (
+	"hash/fnv"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// defaultRefreshWindow matches the margin the oauth2 package allows before
+// a token's expiry.
+const defaultRefreshWindow = 10 * time.Second
+
+// WithRefreshWindow refreshes access tokens once less than window of their
+// lifetime remains, instead of 10 seconds before they expire, so calls
+// never carry a token about to expire. The window, jitter included, is
+// capped at half the token's lifetime, so every token is used for a while
+// before it is refreshed.
+func WithRefreshWindow(window time.Duration) Option {
+	return func(s *settings) {
+		s.refreshWindow = window
+	}
+}
+
+// WithRefreshJitter widens the refresh window by up to jitter, by an amount
+// that differs from token to token, so a fleet of machines started together
+// doesn't refresh all at once.
+func WithRefreshJitter(jitter time.Duration) Option {
+	return func(s *settings) {
+		s.refreshJitter = jitter
+	}
+}
+
+// refreshTime returns when tok is due for refresh, or the zero time if it
+// never expires. The jitter is derived from the access token, so it is
+// stable for a token but spread across machines. The lifetime the cap is
+// taken from is the expires_in the token was issued with; tokens cached
+// without it are not capped until their first refresh.
+func (a *Authenticator) refreshTime(tok *oauth2.Token) time.Time {
+	if tok.Expiry.IsZero() {
+		return time.Time{}
+	}
+	d := a.refreshWindow
+	if d <= 0 {
+		d = defaultRefreshWindow
+	}
+	if a.refreshJitter > 0 {
+		h := fnv.New64a()
+		h.Write([]byte(tok.AccessToken))
+		d += time.Duration(h.Sum64() % uint64(a.refreshJitter))
+	}
+	if limit := time.Duration(tok.ExpiresIn) * time.Second / 2; limit > 0 && d > limit {
+		d = limit
+	}
+
+	return tok.Expiry.Add(-d)
+}
+
+// fresh reports whether tok can be used without refreshing it.
+func (a *Authenticator) fresh(tok *oauth2.Token) bool {
+	if !tok.Valid() {
+		return false
+	}
+	t := a.refreshTime(tok)
+
+	return t.IsZero() || time.Now().Before(t)
+}
+
+// NextRefresh returns when the current access token is due to be
+// refreshed, per WithRefreshWindow and WithRefreshJitter. It returns the
+// zero time if there is no token or it never expires. The refresh happens
+// on the first use of the token after that time.
+func (a *Authenticator) NextRefresh() time.Time {
+	tok := a.currentToken()
+	if tok == nil {
+		return time.Time{}
+	}
+
+	return a.refreshTime(tok)
+}
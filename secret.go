@@ -0,0 +1,138 @@
+package googleauth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const oobRedirectURL = "urn:ietf:wg:oauth:2.0:oob"
+
+type clientSecret struct {
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	RedirectURIs []string `json:"redirect_uris"`
+	AuthURI      string   `json:"auth_uri"`
+	TokenURI     string   `json:"token_uri"`
+}
+
+// clientInfo records what the client secret says about the OAuth client,
+// which decides the consent flows it can use.
+type clientInfo struct {
+	web          bool
+	redirectURIs []string
+}
+
+// loopbackRedirect returns the first loopback redirect URI registered for
+// the client, or "".
+func (c *clientInfo) loopbackRedirect() string {
+	for _, u := range c.redirectURIs {
+		if isLoopbackURL(u) {
+			return u
+		}
+	}
+
+	return ""
+}
+
+// pasteRedirect returns the redirect URI to use when the user copies the
+// code by hand: a loopback URI, whose code can be read from the browser's
+// address bar, or the legacy out-of-band URI for clients still allowed to
+// use it.
+func (c *clientInfo) pasteRedirect() string {
+	if u := c.loopbackRedirect(); u != "" {
+		return u
+	}
+	for _, u := range c.redirectURIs {
+		if u == oobRedirectURL || u == oobRedirectURL+":auto" {
+			return u
+		}
+	}
+
+	return ""
+}
+
+func isLoopbackURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme != "http" {
+		return false
+	}
+	switch u.Hostname() {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	}
+
+	return false
+}
+
+// parseSecret builds an oauth2.Config from a client secret JSON. Unlike
+// google.ConfigFromJSON it accepts clients without redirect URIs, such as
+// those created for TVs and limited input devices, and secrets holding
+// both an installed and a web client, of which want selects one. With
+// ClientAuto, preferWeb breaks the tie.
+func parseSecret(secret []byte, scopes []string, want ClientType, preferWeb bool) (*oauth2.Config, *clientInfo, error) {
+	var j struct {
+		Web       *clientSecret `json:"web"`
+		Installed *clientSecret `json:"installed"`
+	}
+	if err := json.Unmarshal(secret, &j); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSecret, err)
+	}
+
+	if j.Web == nil && j.Installed == nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSecret,
+			errors.New("no credentials found"))
+	}
+	useWeb := j.Installed == nil
+	switch want {
+	case ClientInstalled:
+		if j.Installed == nil {
+			return nil, nil, fmt.Errorf("%w: an installed (\"Desktop app\") client is "+
+				"required but the secret holds only a web client", ErrUnsupportedClient)
+		}
+		useWeb = false
+	case ClientWeb:
+		if j.Web == nil {
+			return nil, nil, fmt.Errorf("%w: a web client is required but the "+
+				"secret holds only an installed client", ErrUnsupportedClient)
+		}
+		useWeb = true
+	default:
+		if j.Web != nil && j.Installed != nil {
+			useWeb = preferWeb
+		}
+	}
+
+	info := &clientInfo{web: useWeb}
+	c := j.Installed
+	if useWeb {
+		c = j.Web
+	}
+	info.redirectURIs = c.RedirectURIs
+
+	config := &oauth2.Config{
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+		Scopes:       scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:       c.AuthURI,
+			TokenURL:      c.TokenURI,
+			DeviceAuthURL: google.Endpoint.DeviceAuthURL,
+		},
+	}
+	if config.Endpoint.AuthURL == "" {
+		config.Endpoint.AuthURL = google.Endpoint.AuthURL
+	}
+	if config.Endpoint.TokenURL == "" {
+		config.Endpoint.TokenURL = google.Endpoint.TokenURL
+	}
+	if len(c.RedirectURIs) > 0 {
+		config.RedirectURL = c.RedirectURIs[0]
+	}
+
+	return config, info, nil
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package googleauth
+
+// checkSecretFile is a no-op where file modes don't express who can read a
+// file.
+func checkSecretFile(file string) error {
+	return nil
+}
@@ -0,0 +1,32 @@
+//go:build unix
+
+package googleauth
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkSecretFile applies the same rule ssh applies to private keys: the
+// file must be owned by the current user (or root) and not accessible to
+// group or others.
+func checkSecretFile(file string) error {
+	fi, err := os.Stat(file)
+	if err != nil {
+		// Reading the file reports the problem.
+		return nil
+	}
+
+	if perm := fi.Mode().Perm(); perm&0077 != 0 {
+		return fmt.Errorf("permissions %04o for %q are too open; "+
+			"it should not be accessible by others (chmod 600)", perm, file)
+	}
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		if uid := int(st.Uid); uid != os.Getuid() && uid != 0 {
+			return fmt.Errorf("%q is owned by uid %d, not the current user", file, uid)
+		}
+	}
+
+	return nil
+}
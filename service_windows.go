@@ -0,0 +1,226 @@
+package googleauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Microsoft/go-winio"
+	"github.com/danieljoos/wincred"
+	"golang.org/x/oauth2"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// WithWindowsService configures an Authenticator to run inside a Windows
+// service: the consent flow is disabled, diagnostics go to the event log
+// under source, and tokens are kept in the Windows Credential Manager.
+// The grant itself must be obtained beforehand, for example by running
+// the same program interactively with the same store.
+func WithWindowsService(source string) Option {
+	return func(s *settings) {
+		l, err := NewEventLogLogger(source)
+		if err != nil {
+			if s.err == nil {
+				s.err = err
+			}
+			return
+		}
+		s.logger = l
+		s.store = &CredentialManagerStore{Prefix: "googleauth:"}
+		s.noPrompt = true
+	}
+}
+
+// EventLogLogger is a Logger that writes informational entries to the
+// Windows event log.
+type EventLogLogger struct {
+	log *eventlog.Log
+}
+
+// NewEventLogLogger opens the event log for source. The source must have
+// been registered, usually when the service is installed.
+func NewEventLogLogger(source string) (*EventLogLogger, error) {
+	l, err := eventlog.Open(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventLogLogger{log: l}, nil
+}
+
+// Printf implements Logger.
+func (l *EventLogLogger) Printf(format string, v ...interface{}) {
+	l.log.Info(1, fmt.Sprintf(format, v...))
+}
+
+// Close closes the event log.
+func (l *EventLogLogger) Close() error {
+	return l.log.Close()
+}
+
+// CredentialManagerStore keeps tokens as generic credentials in the
+// Windows Credential Manager, named Prefix followed by the key.
+type CredentialManagerStore struct {
+	Prefix string
+}
+
+// Get implements TokenStore.
+func (s *CredentialManagerStore) Get(key string) (*oauth2.Token, error) {
+	cred, err := wincred.GetGenericCredential(s.Prefix + key)
+	if err == wincred.ErrElementNotFound {
+		return nil, ErrTokenNotCached
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeToken(cred.CredentialBlob)
+}
+
+// Put implements TokenStore.
+func (s *CredentialManagerStore) Put(key string, tok *oauth2.Token) error {
+	b, err := encodeToken(tok)
+	if err != nil {
+		return err
+	}
+	cred := wincred.NewGenericCredential(s.Prefix + key)
+	cred.CredentialBlob = b
+	cred.Persist = wincred.PersistLocalMachine
+
+	return cred.Write()
+}
+
+// Delete implements TokenStore.
+func (s *CredentialManagerStore) Delete(key string) error {
+	cred, err := wincred.GetGenericCredential(s.Prefix + key)
+	if err == wincred.ErrElementNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return cred.Delete()
+}
+
+// Keys implements TokenLister.
+func (s *CredentialManagerStore) Keys() ([]string, error) {
+	creds, err := wincred.FilteredList(s.Prefix + "*")
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, c := range creds {
+		if k := strings.TrimPrefix(c.TargetName, s.Prefix); !hiddenKey(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// DefaultPipeSecurity grants access to the token pipe to LocalSystem and
+// the Administrators group only.
+const DefaultPipeSecurity = "D:P(A;;GA;;;SY)(A;;GA;;;BA)"
+
+// TokenPipeServer hands out access tokens over a named pipe so other
+// processes on the machine can use a grant held by a service. Each GET
+// request on the pipe is answered with the current token as JSON; the
+// refresh token is never sent.
+type TokenPipeServer struct {
+	Auth *Authenticator
+	// Pipe is the pipe name, such as `\\.\pipe\googleauth`.
+	Pipe string
+	// SecurityDescriptor is an SDDL string controlling who may connect.
+	// Empty means DefaultPipeSecurity.
+	SecurityDescriptor string
+}
+
+type pipeToken struct {
+	AccessToken string    `json:"access_token"`
+	TokenType   string    `json:"token_type,omitempty"`
+	Expiry      time.Time `json:"expiry"`
+}
+
+// Serve accepts connections until ctx is done.
+func (p *TokenPipeServer) Serve(ctx context.Context) error {
+	sd := p.SecurityDescriptor
+	if sd == "" {
+		sd = DefaultPipeSecurity
+	}
+	ln, err := winio.ListenPipe(p.Pipe, &winio.PipeConfig{SecurityDescriptor: sd})
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			tok, err := p.Auth.Token(r.Context())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+			pt := pipeToken{AccessToken: tok.AccessToken, TokenType: tok.Type(), Expiry: tok.Expiry}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(pt)
+		}),
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+
+	err = srv.Serve(ln)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
+}
+
+// PipeTokenSource returns a TokenSource that fetches tokens from a
+// TokenPipeServer listening on pipe.
+func PipeTokenSource(pipe string) oauth2.TokenSource {
+	return oauth2.ReuseTokenSource(nil, &pipeSource{pipe: pipe})
+}
+
+type pipeSource struct {
+	pipe string
+}
+
+func (s *pipeSource) Token() (*oauth2.Token, error) {
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return winio.DialPipeContext(ctx, s.pipe)
+		},
+	}}
+	resp, err := client.Get("http://pipe/token")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("googleauth: token pipe: %s", resp.Status)
+	}
+
+	var pt pipeToken
+	if err := json.NewDecoder(resp.Body).Decode(&pt); err != nil {
+		return nil, err
+	}
+	return &oauth2.Token{
+		AccessToken: pt.AccessToken,
+		TokenType:   pt.TokenType,
+		Expiry:      pt.Expiry,
+	}, nil
+}
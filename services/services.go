@@ -0,0 +1,70 @@
+// Package services creates Google API service instances authorized by
+// googleauth in a single call, with the scopes each API commonly needs.
+//
+// For example:
+//
+//	srv, err := services.NewSheetsService(ctx,
+//		googleauth.WithSecretFile("client_secret.json"))
+//
+// The default scopes can be replaced by passing googleauth.WithScopes among
+// the options.
+package services
+
+import (
+	"context"
+
+	"github.com/jarodmeng/googleauth/v2"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+// NewSheetsService returns a Sheets API service with read and write access
+// to the user's spreadsheets.
+func NewSheetsService(ctx context.Context, opts ...googleauth.Option) (*sheets.Service, error) {
+	o, err := clientOption(ctx, []string{sheets.SpreadsheetsScope}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return sheets.NewService(ctx, o)
+}
+
+// NewDriveService returns a Drive API service with full access to the
+// user's files.
+func NewDriveService(ctx context.Context, opts ...googleauth.Option) (*drive.Service, error) {
+	o, err := clientOption(ctx, []string{drive.DriveScope}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return drive.NewService(ctx, o)
+}
+
+// NewGmailService returns a Gmail API service that can read, send and
+// organize the user's mail, short of deleting it permanently.
+func NewGmailService(ctx context.Context, opts ...googleauth.Option) (*gmail.Service, error) {
+	o, err := clientOption(ctx, []string{gmail.GmailModifyScope}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return gmail.NewService(ctx, o)
+}
+
+// clientOption authorizes a client for scopes, unless opts select others,
+// and returns it as a client library option.
+func clientOption(ctx context.Context, scopes []string, opts []googleauth.Option) (option.ClientOption, error) {
+	opts = append([]googleauth.Option{googleauth.WithScopes(scopes...)}, opts...)
+	a, err := googleauth.NewAuthenticator(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	client, err := a.Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return option.WithHTTPClient(client), nil
+}
@@ -0,0 +1,119 @@
+package googleauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/oauth2/google"
+)
+
+// A Signer signs data as a Google service account, with RSASSA-PKCS1-v1_5
+// over SHA-256.
+type Signer interface {
+	// Email returns the service account's email address.
+	Email() string
+	// Sign returns the signature of b.
+	Sign(ctx context.Context, b []byte) ([]byte, error)
+}
+
+// KeySigner signs with a service account's private key.
+type KeySigner struct {
+	email string
+	key   *rsa.PrivateKey
+}
+
+// NewKeySigner returns a KeySigner for the service account key in JSON
+// form.
+func NewKeySigner(key []byte) (*KeySigner, error) {
+	conf, err := google.JWTConfigFromJSON(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSecret, err)
+	}
+	pk, err := parseRSAKey(conf.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSecret, err)
+	}
+
+	return &KeySigner{email: conf.Email, key: pk}, nil
+}
+
+// Email implements Signer.
+func (s *KeySigner) Email() string {
+	return s.email
+}
+
+// Sign implements Signer.
+func (s *KeySigner) Sign(ctx context.Context, b []byte) ([]byte, error) {
+	h := sha256.Sum256(b)
+
+	return rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, h[:])
+}
+
+// IAMSigner signs through the IAM Credentials API signBlob method, so no
+// private key has to be held. The Client's credentials need the Service
+// Account Token Creator role on the service account.
+type IAMSigner struct {
+	// ServiceAccount is the email of the service account to sign as.
+	ServiceAccount string
+	// Client is an authorized client with the cloud-platform scope, such
+	// as one returned by Authenticator.Client.
+	Client *http.Client
+}
+
+// Email implements Signer.
+func (s *IAMSigner) Email() string {
+	return s.ServiceAccount
+}
+
+// Sign implements Signer.
+func (s *IAMSigner) Sign(ctx context.Context, b []byte) ([]byte, error) {
+	var resp struct {
+		SignedBlob string `json:"signedBlob"`
+	}
+	err := iamCall(ctx, s.Client, s.ServiceAccount, "signBlob",
+		map[string]string{"payload": base64.StdEncoding.EncodeToString(b)}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return base64.StdEncoding.DecodeString(resp.SignedBlob)
+}
+
+// SignJWT returns a JWT with claims signed by the service account's
+// Google-managed key, through the signJwt method. The key ID is set by
+// Google, and an "exp" claim is required no more than 12 hours ahead.
+func (s *IAMSigner) SignJWT(ctx context.Context, claims map[string]interface{}) (string, error) {
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		SignedJWT string `json:"signedJwt"`
+	}
+	err = iamCall(ctx, s.Client, s.ServiceAccount, "signJwt",
+		map[string]string{"payload": string(b)}, &resp)
+	if err != nil {
+		return "", err
+	}
+
+	return resp.SignedJWT, nil
+}
+
+// IAMSigner returns an IAMSigner signing as serviceAccount with the
+// Authenticator's credentials, which need the cloud-platform scope and the
+// Service Account Token Creator role on the account.
+func (a *Authenticator) IAMSigner(ctx context.Context, serviceAccount string) (*IAMSigner, error) {
+	client, err := a.Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IAMSigner{ServiceAccount: serviceAccount, Client: client}, nil
+}
@@ -0,0 +1,100 @@
+package googleauth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// CredentialStatus is a snapshot of the health of an Authenticator's
+// credentials, as returned by Status.
+type CredentialStatus struct {
+	// Cached reports whether a token is held in memory or the store.
+	Cached bool
+	// ExpiresIn is the remaining lifetime of the access token, negative
+	// once it has expired.
+	ExpiresIn time.Duration
+	// RefreshTokenAlive reports whether a refresh just succeeded, so
+	// further access tokens can be obtained without the user.
+	RefreshTokenAlive bool
+	// RefreshErr is why the refresh failed, if it did.
+	RefreshErr error
+	// Account is the user's email address, known when the grant includes
+	// the email scope.
+	Account string
+	// Scopes are the scopes granted, when the token endpoint reported
+	// them.
+	Scopes []string
+	// GrantedAt is when the user consented, or the zero time if unknown.
+	GrantedAt time.Time
+}
+
+// Status reports on the Authenticator's credentials without running the
+// consent flow, for health checks. It probes the refresh token with a
+// refresh, which also renews the access token. The error is non-nil only
+// if the status cannot be determined at all; an unusable credential is
+// reported in the CredentialStatus.
+func (a *Authenticator) Status(ctx context.Context) (_ *CredentialStatus, err error) {
+	defer a.recoverPanic("Status", &err)
+
+	ctx = a.withHTTPClient(ctx)
+	st := &CredentialStatus{}
+	if a.creds != nil {
+		tok, err := (&trackingSource{a: a, src: a.creds.TokenSource}).Token()
+		st.RefreshErr = err
+		if err == nil {
+			st.Cached = true
+			st.RefreshTokenAlive = true
+			st.ExpiresIn = time.Until(tok.Expiry)
+		}
+		return st, nil
+	}
+
+	tok := a.currentToken()
+	if tok == nil {
+		return st, nil
+	}
+	st.Cached = true
+	if tok.RefreshToken == "" {
+		st.RefreshErr = ErrConsentRequired
+	} else if t, err := a.refresh(ctx, true); err != nil {
+		st.RefreshErr = err
+	} else {
+		st.RefreshTokenAlive = true
+		tok = t
+	}
+	st.ExpiresIn = time.Until(tok.Expiry)
+	st.Account = tokenEmail(tok)
+	if s, ok := tok.Extra("scope").(string); ok {
+		st.Scopes = strings.Fields(s)
+	}
+	st.GrantedAt = grantedAt(tok)
+
+	return st, nil
+}
+
+// IsAuthenticated reports whether the Authenticator can produce tokens
+// without the user, per Status.
+func (a *Authenticator) IsAuthenticated(ctx context.Context) bool {
+	st, err := a.Status(ctx)
+
+	return err == nil && st.RefreshTokenAlive
+}
+
+// tokenEmail returns the email claim of tok's ID token, if any. The ID
+// token came straight from the token endpoint, so it isn't verified again.
+func tokenEmail(tok *oauth2.Token) string {
+	raw, _ := tok.Extra("id_token").(string)
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	var c IDTokenClaims
+	if decodeSegment(parts[1], &c) != nil {
+		return ""
+	}
+
+	return c.Email
+}
@@ -0,0 +1,302 @@
+package googleauth
+
+import (
+	"crypto/cipher"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// A TokenStore persists OAuth2 tokens under a key, typically the token file
+// name passed to CreateClient.
+type TokenStore interface {
+	// Get returns the token stored under key, or ErrTokenNotCached if there
+	// is none.
+	Get(key string) (*oauth2.Token, error)
+	// Put stores tok under key, replacing any previous token.
+	Put(key string, tok *oauth2.Token) error
+	// Delete removes the token stored under key. Deleting a missing key is
+	// not an error.
+	Delete(key string) error
+}
+
+// A TokenLister is a TokenStore that can enumerate the keys it holds. All
+// the stores in this package but KeyringStore implement it, KeyValueStore
+// only if its KeyValue does.
+type TokenLister interface {
+	TokenStore
+	// Keys returns the keys of all stored tokens, sorted. Keys starting
+	// with a dot, which the package uses for backups and locks, are left
+	// out.
+	Keys() ([]string, error)
+}
+
+// FileStore stores each token as a JSON file in Dir, encrypted if the
+// Authenticator was given WithCacheKey or a similar option.
+type FileStore struct {
+	Dir string
+
+	aead      cipher.AEAD // nil for plaintext files
+	legacyDir string      // where tokens missing from Dir may still be
+}
+
+// NewFileStore returns a FileStore rooted at dir, creating the directory if
+// needed. An empty dir selects $GOOGLEAUTH_CACHE_DIR if set and the
+// googleauth directory in the user's configuration directory otherwise:
+// $XDG_CONFIG_HOME or ~/.config on Linux, ~/Library/Application Support on
+// macOS and %AppData% on Windows. Snaps and Flatpak apps use the sandbox's
+// own directory. On Android there is no home directory and apps pass their
+// files directory.
+//
+// With the default directory, tokens cached in ~/.credentials by earlier
+// versions are moved over the first time they are read.
+func NewFileStore(dir string) (*FileStore, error) {
+	var legacy string
+	if dir == "" {
+		var err error
+		dir, err = defaultCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		legacy = legacyCacheDir()
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	return &FileStore{Dir: dir, legacyDir: legacy}, nil
+}
+
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.Dir, url.QueryEscape(key))
+}
+
+// storeLockFile is locked, shared by readers and exclusively by writers,
+// around every access to a FileStore's files by any process. Its name
+// starts with a dot so Keys skips it.
+const storeLockFile = ".lock"
+
+// lock takes the advisory lock of Dir and returns its release. Locking is
+// best effort: the atomic replacement of token files already keeps
+// readers from seeing partial writes.
+func (s *FileStore) lock(exclusive bool) func() {
+	f, err := os.OpenFile(filepath.Join(s.Dir, storeLockFile), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return func() {}
+	}
+	if err := lockFile(f, exclusive); err != nil {
+		f.Close()
+		return func() {}
+	}
+
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}
+}
+
+// read returns the contents of the file of key.
+func (s *FileStore) read(key string) ([]byte, error) {
+	defer s.lock(false)()
+
+	return ioutil.ReadFile(s.path(key))
+}
+
+// Get implements TokenStore.
+func (s *FileStore) Get(key string) (*oauth2.Token, error) {
+	b, err := s.read(key)
+	if os.IsNotExist(err) && s.legacyDir != "" {
+		return s.migrate(key)
+	}
+	if os.IsNotExist(err) {
+		return nil, ErrTokenNotCached
+	}
+	if err != nil {
+		return nil, err
+	}
+	b, encrypted, err := s.open(key, b)
+	if err != nil {
+		return nil, err
+	}
+	tok, err := decodeToken(b)
+	if err != nil {
+		return nil, err
+	}
+	if s.aead != nil && !encrypted {
+		// Migrate a token cached before encryption was turned on.
+		if err := s.Put(key, tok); err != nil {
+			return nil, err
+		}
+	}
+
+	return tok, nil
+}
+
+// migrate moves the token under key from the legacy directory into Dir.
+func (s *FileStore) migrate(key string) (*oauth2.Token, error) {
+	legacy := filepath.Join(s.legacyDir, url.QueryEscape(key))
+	b, err := ioutil.ReadFile(legacy)
+	if os.IsNotExist(err) {
+		return nil, ErrTokenNotCached
+	}
+	if err != nil {
+		return nil, err
+	}
+	b, _, err = s.open(key, b)
+	if err != nil {
+		return nil, err
+	}
+	tok, err := decodeToken(b)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Put(key, tok); err != nil {
+		return nil, err
+	}
+	os.Remove(legacy)
+
+	return tok, nil
+}
+
+// Put implements TokenStore. The token is written to a temporary file that
+// then replaces the previous one, so readers never see a partial token.
+func (s *FileStore) Put(key string, tok *oauth2.Token) error {
+	b, err := encodeToken(tok)
+	if err != nil {
+		return err
+	}
+	if s.aead != nil {
+		b, err = s.seal(key, b)
+		if err != nil {
+			return err
+		}
+	}
+	defer s.lock(true)()
+
+	return writeFileAtomic(s.path(key), b)
+}
+
+// writeFileAtomic replaces the file at path with one holding b, readable
+// only by the user.
+func writeFileAtomic(path string, b []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(b)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// Keys implements TokenLister. Temporary files and locks, whose names
+// start with a dot, are skipped.
+func (s *FileStore) Keys() ([]string, error) {
+	fis, err := ioutil.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, fi := range fis {
+		if fi.IsDir() || strings.HasPrefix(fi.Name(), ".") {
+			continue
+		}
+		key, err := url.QueryUnescape(fi.Name())
+		if err != nil {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// Delete implements TokenStore.
+func (s *FileStore) Delete(key string) error {
+	defer s.lock(true)()
+
+	err := os.Remove(s.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// MemoryStore keeps tokens in process memory only. The zero value is an
+// empty store ready to use. It is safe for concurrent use.
+type MemoryStore struct {
+	mu     sync.Mutex
+	tokens map[string]oauth2.Token
+}
+
+// Get implements TokenStore.
+func (s *MemoryStore) Get(key string) (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tokens[key]
+	if !ok {
+		return nil, ErrTokenNotCached
+	}
+
+	return &t, nil
+}
+
+// Put implements TokenStore.
+func (s *MemoryStore) Put(key string, tok *oauth2.Token) error {
+	if tok == nil {
+		return ErrInvalidToken
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tokens == nil {
+		s.tokens = make(map[string]oauth2.Token)
+	}
+	s.tokens[key] = *tok
+
+	return nil
+}
+
+// Delete implements TokenStore.
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tokens, key)
+
+	return nil
+}
+
+// Keys implements TokenLister.
+func (s *MemoryStore) Keys() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.tokens))
+	for k := range s.tokens {
+		if !hiddenKey(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
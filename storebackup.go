@@ -0,0 +1,180 @@
+package googleauth
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/oauth2"
+)
+
+// backupMagic starts every store backup, and names its format version.
+// It is followed by the name of the KDF and a newline.
+const backupMagic = "googleauth-backup-v1\n"
+
+// The key derivation functions of backups.
+const (
+	kdfScrypt = "scrypt"
+	kdfPBKDF2 = "pbkdf2-sha256"
+)
+
+// ErrBadBackup is returned by RestoreStore when the backup is damaged or
+// the password is wrong.
+var ErrBadBackup = errors.New("googleauth: backup is corrupt or the password is wrong")
+
+// backupKey derives the encryption key from password with kdf.
+func backupKey(kdf, password string, salt []byte) ([]byte, error) {
+	switch kdf {
+	case kdfScrypt:
+		return scrypt.Key([]byte(password), salt, 1<<15, 8, 1, 32)
+	case kdfPBKDF2:
+		return pbkdf2.Key([]byte(password), salt, 600000, 32, sha256.New), nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown key derivation %q", ErrBadBackup, kdf)
+}
+
+// BackupStore writes every token in the Authenticator's store, including
+// other keys and profiles, to w, encrypted with a key derived from
+// password by scrypt, or by PBKDF2-SHA256 under WithFIPSMode. The store
+// must implement TokenLister. Backups are for disaster
+// recovery of machines whose grants are hard to obtain again; anyone with
+// the backup and password can use the grants.
+func (a *Authenticator) BackupStore(w io.Writer, password string) error {
+	l, ok := a.store.(TokenLister)
+	if !ok {
+		return ErrNotListable
+	}
+	keys, err := l.Keys()
+	if err != nil {
+		return err
+	}
+
+	entries := map[string]json.RawMessage{}
+	for _, k := range keys {
+		tok, err := a.store.Get(k)
+		if errors.Is(err, ErrTokenNotCached) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("googleauth: backing up %q: %v", k, err)
+		}
+		b, err := encodeToken(tok)
+		if err != nil {
+			return err
+		}
+		entries[k] = b
+	}
+	plain, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	kdf := kdfScrypt
+	if a.fips {
+		kdf = kdfPBKDF2
+	}
+	gcm, err := backupCipher(kdf, password, salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(backupMagic)
+	buf.WriteString(kdf + "\n")
+	buf.Write(salt)
+	buf.Write(nonce)
+	// The header is authenticated too, so it cannot be swapped.
+	buf.Write(gcm.Seal(nil, nonce, plain, buf.Bytes()))
+	_, err = w.Write(buf.Bytes())
+
+	return err
+}
+
+// RestoreStore puts every token from a backup written by BackupStore into
+// the Authenticator's store, replacing tokens with the same keys. Nothing
+// is written unless the whole backup decrypts and verifies. Under
+// WithFIPSMode only backups keyed by PBKDF2 are accepted.
+func (a *Authenticator) RestoreStore(r io.Reader, password string) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(b, []byte(backupMagic)) {
+		return ErrBadBackup
+	}
+	i := bytes.IndexByte(b[len(backupMagic):], '\n')
+	if i < 0 {
+		return ErrBadBackup
+	}
+	kdf := string(b[len(backupMagic) : len(backupMagic)+i])
+	n := len(backupMagic) + i + 1 // length of the magic and KDF name
+	if a.fips && kdf != kdfPBKDF2 {
+		return fmt.Errorf("googleauth: backup key derived by %s, which WithFIPSMode does not allow", kdf)
+	}
+	hdr := n + 16 + 12
+	if len(b) < hdr {
+		return ErrBadBackup
+	}
+	salt := b[n : n+16]
+	nonce := b[n+16 : hdr]
+	gcm, err := backupCipher(kdf, password, salt)
+	if err != nil {
+		return err
+	}
+	plain, err := gcm.Open(nil, nonce, b[hdr:], b[:hdr])
+	if err != nil {
+		return ErrBadBackup
+	}
+
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(plain, &entries); err != nil {
+		return ErrBadBackup
+	}
+	toks := make(map[string]*oauth2.Token, len(entries))
+	for k, raw := range entries {
+		toks[k], err = decodeToken(raw)
+		if err != nil {
+			return ErrBadBackup
+		}
+	}
+	for k, tok := range toks {
+		if err := a.store.Put(k, tok); err != nil {
+			return fmt.Errorf("googleauth: restoring %q: %v", k, err)
+		}
+	}
+	a.setToken(nil)
+	a.logf("googleauth: restored %d tokens", len(entries))
+
+	return nil
+}
+
+func backupCipher(kdf, password string, salt []byte) (cipher.AEAD, error) {
+	key, err := backupKey(kdf, password, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,59 @@
+package googleauth
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestBackupKDF(t *testing.T) {
+	ctx := context.Background()
+	for _, fips := range []bool{false, true} {
+		opts := []Option{WithSecret([]byte(testSecret)), WithoutCache()}
+		if fips {
+			opts = append(opts, WithFIPSMode())
+		}
+		a, err := NewAuthenticator(ctx, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := a.store.Put("k", &oauth2.Token{AccessToken: "at", RefreshToken: "rt"}); err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := a.BackupStore(&buf, "pw"); err != nil {
+			t.Fatal(err)
+		}
+		want := backupMagic + kdfScrypt + "\n"
+		if fips {
+			want = backupMagic + kdfPBKDF2 + "\n"
+		}
+		if !strings.HasPrefix(buf.String(), want) {
+			t.Fatalf("fips %v: backup starts %q, want %q", fips, buf.String()[:len(want)], want)
+		}
+
+		b, err := NewAuthenticator(ctx, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := b.RestoreStore(bytes.NewReader(buf.Bytes()), "pw"); err != nil {
+			t.Fatalf("fips %v: %v", fips, err)
+		}
+		if tok, err := b.store.Get("k"); err != nil || tok.RefreshToken != "rt" {
+			t.Fatalf("fips %v: restored %v, %v", fips, tok, err)
+		}
+
+		if !fips {
+			f, err := NewAuthenticator(ctx, WithSecret([]byte(testSecret)), WithoutCache(), WithFIPSMode())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := f.RestoreStore(bytes.NewReader(buf.Bytes()), "pw"); err == nil {
+				t.Fatal("scrypt backup restored under FIPS mode")
+			}
+		}
+	}
+}
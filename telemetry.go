@@ -0,0 +1,101 @@
+package googleauth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"runtime"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// A TelemetrySink receives a report after each consent flow and refresh.
+// Telemetry is off unless a sink is set with WithTelemetry, and googleauth
+// never sends reports anywhere itself.
+type TelemetrySink interface {
+	Report(r TelemetryReport)
+}
+
+// TelemetrySinkFunc adapts a function to TelemetrySink.
+type TelemetrySinkFunc func(r TelemetryReport)
+
+// Report implements TelemetrySink.
+func (f TelemetrySinkFunc) Report(r TelemetryReport) {
+	f(r)
+}
+
+// A TelemetryReport describes the outcome of an operation. It carries no
+// tokens, account, client or scope information, so reports from many
+// machines can be aggregated without identifying users.
+type TelemetryReport struct {
+	// Operation is "consent" or "refresh".
+	Operation string
+	// Flow is the configured consent flow, or "receiver" for a
+	// CodeReceiver set with WithCodeReceiver. Empty for refreshes.
+	Flow string
+	// Success reports whether the operation obtained a token.
+	Success bool
+	// ErrorClass classifies the failure, see ErrorClass. Empty on
+	// success.
+	ErrorClass string
+	Duration   time.Duration
+	// OS is runtime.GOOS.
+	OS string
+}
+
+// WithTelemetry reports the outcome of every consent flow and refresh to
+// sink.
+func WithTelemetry(sink TelemetrySink) Option {
+	return func(s *settings) {
+		s.telemetry = sink
+	}
+}
+
+// ErrorClass returns a coarse, stable class for err that reveals nothing
+// about the user: "consent_required", "reauthorization_required",
+// "canceled", "timeout", "network", "oauth_<error code>",
+// "unsupported_client", "invalid_secret", "internal" or "other".
+func ErrorClass(err error) string {
+	var rerr *oauth2.RetrieveError
+	var nerr net.Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrConsentRequired):
+		return "consent_required"
+	case errors.Is(err, ErrReauthorizationRequired):
+		return "reauthorization_required"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, ErrUnsupportedClient):
+		return "unsupported_client"
+	case errors.Is(err, ErrInvalidSecret):
+		return "invalid_secret"
+	case errors.Is(err, ErrInternal):
+		return "internal"
+	case errors.As(err, &rerr) && rerr.ErrorCode != "":
+		return "oauth_" + rerr.ErrorCode
+	case errors.As(err, &nerr):
+		return "network"
+	}
+
+	return "other"
+}
+
+// report sends the outcome of op, started at start, to the sink.
+func (a *Authenticator) report(op, flow string, start time.Time, err error) {
+	if a.telemetry == nil {
+		return
+	}
+	a.telemetry.Report(TelemetryReport{
+		Operation:  op,
+		Flow:       flow,
+		Success:    err == nil,
+		ErrorClass: ErrorClass(err),
+		Duration:   time.Since(start),
+		OS:         runtime.GOOS,
+	})
+}
@@ -0,0 +1,145 @@
+package googleauth
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Metadata googleauth records about a grant is kept in the token's Extra
+// data under these keys.
+const (
+	extraGrantedAt = "googleauth_granted_at"
+	extraScopes    = "googleauth_scopes" // the scopes requested, space-separated
+)
+
+// keptExtras lists the Extra fields that survive storage and refreshes:
+// the token endpoint's own id_token and scope, and the recorded metadata.
+var keptExtras = []string{"id_token", "scope", extraGrantedAt, extraScopes}
+
+// tokenJSON is the stored form of a token: the usual oauth2.Token fields,
+// so existing cache files still load, plus the recorded metadata.
+type tokenJSON struct {
+	oauth2.Token
+	IDToken   string `json:"id_token,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	GrantedAt int64  `json:"granted_at,omitempty"`
+	Scopes    string `json:"requested_scopes,omitempty"`
+}
+
+func encodeToken(tok *oauth2.Token) ([]byte, error) {
+	if tok == nil {
+		return nil, ErrInvalidToken
+	}
+	j := tokenJSON{Token: *tok}
+	j.IDToken, _ = tok.Extra("id_token").(string)
+	j.Scope, _ = tok.Extra("scope").(string)
+	if t := grantedAt(tok); !t.IsZero() {
+		j.GrantedAt = t.Unix()
+	}
+	j.Scopes, _ = tok.Extra(extraScopes).(string)
+
+	return json.Marshal(j)
+}
+
+func decodeToken(b []byte) (*oauth2.Token, error) {
+	var j tokenJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return nil, err
+	}
+
+	extra := map[string]interface{}{}
+	if j.IDToken != "" {
+		extra["id_token"] = j.IDToken
+	}
+	if j.Scope != "" {
+		extra["scope"] = j.Scope
+	}
+	if j.GrantedAt != 0 {
+		extra[extraGrantedAt] = j.GrantedAt
+	}
+	if j.Scopes != "" {
+		extra[extraScopes] = j.Scopes
+	}
+
+	return j.Token.WithExtra(extra), nil
+}
+
+// withExtra returns a copy of tok whose Extra data holds the kept fields
+// of tok and then of each of from, in that order of precedence, plus kv.
+func withExtra(tok *oauth2.Token, kv map[string]interface{}, from ...*oauth2.Token) *oauth2.Token {
+	extra := map[string]interface{}{}
+	for _, t := range append([]*oauth2.Token{tok}, from...) {
+		if t == nil {
+			continue
+		}
+		for _, k := range keptExtras {
+			if _, ok := extra[k]; ok {
+				continue
+			}
+			if v := t.Extra(k); v != nil && v != "" {
+				extra[k] = v
+			}
+		}
+	}
+	for k, v := range kv {
+		extra[k] = v
+	}
+
+	return tok.WithExtra(extra)
+}
+
+// grantedAt returns when the user consented to the grant tok belongs to,
+// or the zero time if unknown.
+func grantedAt(tok *oauth2.Token) time.Time {
+	if tok == nil {
+		return time.Time{}
+	}
+	var sec int64
+	switch v := tok.Extra(extraGrantedAt).(type) {
+	case int64:
+		sec = v
+	case float64:
+		sec = int64(v)
+	case json.Number:
+		sec, _ = v.Int64()
+	}
+	if sec == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(sec, 0)
+}
+
+// tokenScopes returns the scopes tok's grant holds, as the token endpoint
+// reported them, falling back to the scopes it was requested with. With
+// granular consent the user may have declined some of those. It returns
+// nil if neither is known.
+func tokenScopes(tok *oauth2.Token) []string {
+	if s, ok := tok.Extra("scope").(string); ok && s != "" {
+		return strings.Fields(s)
+	}
+	if s, ok := tok.Extra(extraScopes).(string); ok && s != "" {
+		return strings.Fields(s)
+	}
+
+	return nil
+}
+
+// scopeAliases maps the short scopes Google reports under their full
+// names when granted.
+var scopeAliases = map[string]string{
+	"email":   "https://www.googleapis.com/auth/userinfo.email",
+	"profile": "https://www.googleapis.com/auth/userinfo.profile",
+}
+
+// canonicalScope returns the name Google reports scope under.
+func canonicalScope(scope string) string {
+	if full, ok := scopeAliases[scope]; ok {
+		return full
+	}
+
+	return scope
+}
@@ -0,0 +1,59 @@
+package googleauth
+
+import (
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestScopesChanged(t *testing.T) {
+	a := &Authenticator{config: &oauth2.Config{Scopes: []string{"openid", "email", "https://www.googleapis.com/auth/drive"}}}
+	tests := []struct {
+		name  string
+		extra map[string]interface{}
+		want  bool
+	}{
+		{"unknown", nil, false},
+		{"granted", map[string]interface{}{
+			"scope": "openid https://www.googleapis.com/auth/userinfo.email https://www.googleapis.com/auth/drive",
+		}, false},
+		{"declined", map[string]interface{}{
+			"scope":     "openid https://www.googleapis.com/auth/userinfo.email",
+			extraScopes: "openid email https://www.googleapis.com/auth/drive",
+		}, true},
+		{"requested only", map[string]interface{}{
+			extraScopes: "openid email",
+		}, true},
+	}
+	for _, tt := range tests {
+		tok := (&oauth2.Token{AccessToken: "at"}).WithExtra(tt.extra)
+		if got := a.scopesChanged(tok); got != tt.want {
+			t.Errorf("%s: scopesChanged = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTokenRoundTrip(t *testing.T) {
+	tok := withExtra(&oauth2.Token{AccessToken: "at", RefreshToken: "rt", ExpiresIn: 3600}, map[string]interface{}{
+		"scope":        "a b",
+		extraScopes:    "a b c",
+		extraGrantedAt: int64(1700000000),
+	})
+	b, err := encodeToken(tok)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeToken(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.RefreshToken != "rt" || got.ExpiresIn != 3600 {
+		t.Errorf("decoded token = %+v", got)
+	}
+	if s := tokenScopes(got); len(s) != 2 {
+		t.Errorf("tokenScopes = %v, want the granted a b", s)
+	}
+	if g := grantedAt(got); g.Unix() != 1700000000 {
+		t.Errorf("grantedAt = %v", g)
+	}
+}
@@ -0,0 +1,136 @@
+package googleauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const tokenInfoURL = "https://oauth2.googleapis.com/tokeninfo"
+
+// AccessTokenInfo is what Google reports about an access token, as
+// returned by VerifyToken.
+type AccessTokenInfo struct {
+	// Audience is the client ID the token was issued to.
+	Audience string
+	// Scopes are the scopes the token grants.
+	Scopes []string
+	// Expiry is when Google stops accepting the token.
+	Expiry time.Time
+	// Email is the account's address, if the email scope was granted.
+	Email string
+}
+
+// VerifyToken asks Google whether the current access token is still
+// accepted, without refreshing it. Long-running programs can call it to
+// detect a token revoked early and call RefreshNow. It fails with
+// ErrTokenNotCached if there is no token and with ErrInvalidToken if Google
+// rejects it. Tokens of another WithIssuer provider cannot be checked.
+func (a *Authenticator) VerifyToken(ctx context.Context) (*AccessTokenInfo, error) {
+	if a.issuer != "" && a.issuer != googleIssuer {
+		return nil, fmt.Errorf("googleauth: cannot verify tokens of issuer %s with Google", a.issuer)
+	}
+	tok := a.currentToken()
+	if tok == nil || tok.AccessToken == "" {
+		return nil, ErrTokenNotCached
+	}
+
+	return tokenInfo(a.withHTTPClient(ctx), tok.AccessToken)
+}
+
+func tokenInfo(ctx context.Context, accessToken string) (*AccessTokenInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenInfoURL,
+		strings.NewReader(url.Values{"access_token": {accessToken}}.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := contextClient(ctx).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var j struct {
+		Aud   string `json:"aud"`
+		Scope string `json:"scope"`
+		Exp   string `json:"exp"`
+		Email string `json:"email"`
+		Error string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&j); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusBadRequest {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidToken, j.Error)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("googleauth: tokeninfo: %s", resp.Status)
+	}
+	exp, _ := strconv.ParseInt(j.Exp, 10, 64)
+
+	return &AccessTokenInfo{
+		Audience: j.Aud,
+		Scopes:   strings.Fields(j.Scope),
+		Expiry:   time.Unix(exp, 0),
+		Email:    j.Email,
+	}, nil
+}
+
+// CachedToken describes a token in the cache, as returned by TokenInfo.
+type CachedToken struct {
+	// Expiry is when the access token expires.
+	Expiry time.Time
+	// HasRefreshToken reports whether new access tokens can be obtained
+	// without the user.
+	HasRefreshToken bool
+	// Scopes are the scopes granted. They come from Google while the
+	// access token is valid, and from the cache otherwise.
+	Scopes []string
+	// Email is the account's address, known when the grant includes the
+	// email scope.
+	Email string
+	// GrantedAt is when the user consented, or the zero time if unknown.
+	GrantedAt time.Time
+}
+
+// TokenInfo reports on the token cached under tokenFile, so that
+// programs can show who is signed in and for how long. No client secret is
+// needed and the token is not refreshed. The store is found as by
+// RevokeToken, from opts. It fails with ErrTokenNotCached if there is no
+// token.
+func TokenInfo(ctx context.Context, tokenFile string, opts ...Option) (*CachedToken, error) {
+	ctx, store, key, err := cachedTokenOpts(ctx, tokenFile, opts)
+	if err != nil {
+		return nil, err
+	}
+	tok, err := store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	ct := &CachedToken{
+		Expiry:          tok.Expiry,
+		HasRefreshToken: tok.RefreshToken != "",
+		Scopes:          tokenScopes(tok),
+		Email:           tokenEmail(tok),
+		GrantedAt:       grantedAt(tok),
+	}
+	if tok.Valid() {
+		// Best effort: the cached metadata stands in when Google cannot
+		// be asked.
+		if info, err := tokenInfo(ctx, tok.AccessToken); err == nil {
+			ct.Scopes = info.Scopes
+			if info.Email != "" {
+				ct.Email = info.Email
+			}
+		}
+	}
+
+	return ct, nil
+}
@@ -0,0 +1,86 @@
+package googleauth
+
+import (
+	"context"
+	"errors"
+)
+
+// OpenIDScopes are the scopes WithOpenID adds.
+var OpenIDScopes = []string{"openid", "email", "profile"}
+
+// WithOpenID adds OpenIDScopes to the scopes requested, so the token
+// endpoint issues an ID token identifying the user alongside the access
+// token. UserInfo returns its verified claims. It may come before or after
+// WithScopes.
+func WithOpenID() Option {
+	return func(s *settings) {
+		s.openID = true
+	}
+}
+
+// withOpenIDScopes returns scopes with OpenIDScopes added, once each.
+func withOpenIDScopes(scopes []string) []string {
+	out := append([]string(nil), scopes...)
+	for _, o := range OpenIDScopes {
+		found := false
+		for _, s := range scopes {
+			if s == o {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, o)
+		}
+	}
+
+	return out
+}
+
+// UserInfo identifies the user who granted the token, from the claims of
+// the ID token.
+type UserInfo struct {
+	// Subject is the account's stable, unique ID.
+	Subject       string
+	Email         string
+	EmailVerified bool
+	// HostedDomain is the Google Workspace domain of the account, if any.
+	HostedDomain string
+	Name         string
+	Picture      string
+}
+
+var errNoIDToken = errors.New("googleauth: no ID token; request the openid scope, for example with WithOpenID")
+
+// UserInfo returns who granted the current token, from the ID token the
+// token endpoint issued with it, after verifying its signature, issuer and
+// audience. The token is obtained or refreshed first as Token does.
+func (a *Authenticator) UserInfo(ctx context.Context) (*UserInfo, error) {
+	if a.config == nil {
+		return nil, errNoOAuthClient
+	}
+	tok, err := a.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	raw, _ := tok.Extra("id_token").(string)
+	if raw == "" {
+		return nil, errNoIDToken
+	}
+	v := &IDTokenVerifier{Issuer: a.issuer, Audience: a.config.ClientID}
+	c, err := v.Verify(a.withHTTPClient(ctx), raw)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &UserInfo{
+		Subject:       c.Subject,
+		Email:         c.Email,
+		EmailVerified: c.EmailVerified,
+	}
+	info.HostedDomain, _ = c.Claims["hd"].(string)
+	info.Name, _ = c.Claims["name"].(string)
+	info.Picture, _ = c.Claims["picture"].(string)
+
+	return info, nil
+}
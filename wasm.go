@@ -0,0 +1,154 @@
+//go:build js && wasm
+
+package googleauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"syscall/js"
+)
+
+// In the browser the default store is localStorage, unless a cache
+// directory is given explicitly (which only makes sense under Node).
+// HTTP requests need no special handling: net/http already uses the Fetch
+// API on js/wasm.
+func newDefaultStore(cacheDir string) (TokenStore, error) {
+	if cacheDir != "" {
+		return NewFileStore(cacheDir)
+	}
+
+	return NewLocalStorageStore("googleauth:"), nil
+}
+
+// NewLocalStorageStore returns a TokenStore backed by the browser's
+// window.localStorage, with every key prefixed by prefix.
+func NewLocalStorageStore(prefix string) *KeyValueStore {
+	return NewKeyValueStore(localStorage{prefix: prefix})
+}
+
+type localStorage struct {
+	prefix string
+}
+
+func (s localStorage) Get(key string) (b []byte, err error) {
+	err = catchJS(func() {
+		v := js.Global().Get("localStorage").Call("getItem", s.prefix+key)
+		if !v.IsNull() {
+			b = []byte(v.String())
+		}
+	})
+	return b, err
+}
+
+func (s localStorage) Put(key string, value []byte) error {
+	return catchJS(func() {
+		js.Global().Get("localStorage").Call("setItem", s.prefix+key, string(value))
+	})
+}
+
+func (s localStorage) Delete(key string) error {
+	return catchJS(func() {
+		js.Global().Get("localStorage").Call("removeItem", s.prefix+key)
+	})
+}
+
+func (s localStorage) Keys() (keys []string, err error) {
+	err = catchJS(func() {
+		ls := js.Global().Get("localStorage")
+		for i := 0; i < ls.Get("length").Int(); i++ {
+			if k := ls.Call("key", i).String(); strings.HasPrefix(k, s.prefix) {
+				keys = append(keys, strings.TrimPrefix(k, s.prefix))
+			}
+		}
+	})
+	return keys, err
+}
+
+// catchJS converts a JavaScript exception thrown during f, such as a
+// SecurityError from disabled storage, into an error.
+func catchJS(f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			jsErr, ok := r.(js.Error)
+			if !ok {
+				panic(r)
+			}
+			err = fmt.Errorf("googleauth: %v", jsErr)
+		}
+	}()
+	f()
+
+	return nil
+}
+
+// PopupReceiver is a CodeReceiver for Go programs running in a web page. It
+// opens the consent page in a popup window and waits for the redirect page
+// to post its address back with
+//
+//	window.opener.postMessage(location.href, location.origin)
+//
+// The redirect page must be on the same origin as the calling page and
+// registered for the client. ReceiveCode blocks, so it must not be called
+// directly from a js.Func callback.
+type PopupReceiver struct {
+	// RedirectURL is the address of the redirect page.
+	RedirectURL string
+}
+
+// ReceiveCode implements CodeReceiver.
+func (p *PopupReceiver) ReceiveCode(ctx context.Context, req *AuthRequest) (string, error) {
+	if p.RedirectURL == "" {
+		return "", errors.New("googleauth: PopupReceiver has no RedirectURL")
+	}
+	req.Config.RedirectURL = p.RedirectURL
+
+	window := js.Global()
+	origin := window.Get("location").Get("origin").String()
+	results := make(chan codeResult, 1)
+	onMessage := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		ev := args[0]
+		data := ev.Get("data")
+		if ev.Get("origin").String() != origin || data.Type() != js.TypeString ||
+			!strings.HasPrefix(data.String(), p.RedirectURL) {
+			return nil
+		}
+		u, err := url.Parse(data.String())
+		if err != nil {
+			return nil
+		}
+		q := u.Query()
+		if q.Get("state") != req.State {
+			return nil
+		}
+		var res codeResult
+		if e := q.Get("error"); e != "" {
+			res.err = fmt.Errorf("googleauth: authorization failed: %s", e)
+		} else {
+			res.code = q.Get("code")
+		}
+		select {
+		case results <- res:
+		default:
+		}
+		return nil
+	})
+	defer onMessage.Release()
+	window.Call("addEventListener", "message", onMessage)
+	defer window.Call("removeEventListener", "message", onMessage)
+
+	popup := window.Call("open", req.AuthCodeURL(), "googleauth", "popup,width=500,height=650")
+	if popup.IsNull() || popup.IsUndefined() {
+		return "", errors.New("googleauth: the consent popup was blocked")
+	}
+	defer popup.Call("close")
+
+	select {
+	case res := <-results:
+		return res.code, res.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
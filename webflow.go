@@ -0,0 +1,204 @@
+package googleauth
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// webAuthTTL is how long the consent URL returned by AuthCodeURL can be
+// completed.
+const webAuthTTL = 10 * time.Minute
+
+// webUsers is how many users' Authenticators a WebFlow keeps in memory.
+// Beyond it the least recently used are dropped; their tokens stay in the
+// store.
+const webUsers = 1024
+
+// A WebFlow runs the authorization code flow of a web application client
+// on behalf of the users of a web server, and keeps each user's token in
+// the TokenStore under the profile named by an application-supplied user
+// or session ID. It is safe for concurrent use.
+//
+// Consent URLs are tracked in memory, so the callback must reach the
+// process that created the URL.
+type WebFlow struct {
+	a *Authenticator
+
+	mu      sync.Mutex
+	pending map[string]webAuth       // by state
+	users   map[string]*list.Element // by user ID, in lru
+	lru     *list.List               // of *webUser, most recently used first
+}
+
+type webAuth struct {
+	userID string
+	expiry time.Time
+}
+
+type webUser struct {
+	id string
+	a  *Authenticator
+}
+
+// NewWebFlow creates a WebFlow for the web client of the client secret
+// given in opts. Google redirects users to redirectURL, which must be
+// registered for the client and served by CallbackHandler; empty means the
+// first redirect URI of the secret. The consent flow options of opts are
+// ignored.
+func NewWebFlow(ctx context.Context, redirectURL string, opts ...Option) (*WebFlow, error) {
+	opts = append(append([]Option{}, opts...), WithClientType(ClientWeb), WithoutInteraction())
+	a, err := NewAuthenticator(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if a.config == nil {
+		return nil, fmt.Errorf("%w: no web client secret given", ErrInvalidSecret)
+	}
+	if redirectURL != "" {
+		a.config.RedirectURL = redirectURL
+	}
+
+	return &WebFlow{
+		a:       a,
+		pending: make(map[string]webAuth),
+		users:   make(map[string]*list.Element),
+		lru:     list.New(),
+	}, nil
+}
+
+// AuthCodeURL returns the consent page to send the user identified by
+// userID to. The user ID can't contain a colon or be DefaultProfile, whose
+// token is the WebFlow's own.
+func (f *WebFlow) AuthCodeURL(userID string) (string, error) {
+	if _, err := f.user(userID); err != nil {
+		return "", err
+	}
+	state, err := randomState()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	f.mu.Lock()
+	for s, p := range f.pending {
+		if now.After(p.expiry) {
+			delete(f.pending, s)
+		}
+	}
+	f.pending[state] = webAuth{userID: userID, expiry: now.Add(webAuthTTL)}
+	f.mu.Unlock()
+
+	opts := append([]oauth2.AuthCodeOption{oauth2.AccessTypeOffline}, f.a.authOpts...)
+
+	return f.a.config.AuthCodeURL(state, opts...), nil
+}
+
+var (
+	errUnknownState = errors.New("googleauth: unknown or expired authorization request")
+	errWrongSession = errors.New("googleauth: authorization request belongs to another session")
+)
+
+// HandleCallback completes the flow for the redirect r from the consent
+// page, storing the new token. sessionUserID is the user of the session r
+// was made in, as the application knows it from its own session cookie.
+// Unless it is the user AuthCodeURL was called for, the redirect is
+// rejected before the code is exchanged: otherwise anyone could send a
+// user to a callback carrying their own grant and have it stored as the
+// user's.
+func (f *WebFlow) HandleCallback(r *http.Request, sessionUserID string) error {
+	q := r.URL.Query()
+	f.mu.Lock()
+	p, ok := f.pending[q.Get("state")]
+	delete(f.pending, q.Get("state"))
+	f.mu.Unlock()
+	switch {
+	case !ok || time.Now().After(p.expiry):
+		return errUnknownState
+	case p.userID != sessionUserID:
+		return errWrongSession
+	case q.Get("error") != "":
+		return fmt.Errorf("googleauth: authorization failed: %s", q.Get("error"))
+	case q.Get("code") == "":
+		return errors.New("googleauth: redirect has no authorization code")
+	}
+
+	user, err := f.user(p.userID)
+	if err != nil {
+		return err
+	}
+	ctx := f.a.withHTTPClient(r.Context())
+	tok, err := f.a.withRetry(ctx, "code exchange", isTransientExchange, func() (*oauth2.Token, error) {
+		return f.a.config.Exchange(ctx, q.Get("code"), f.a.exchOpts...)
+	})
+	if err != nil {
+		return exchangeError("exchange", err)
+	}
+	tok = user.newGrant(tok)
+	if err := user.putToken(tok); err != nil {
+		return err
+	}
+	user.setToken(tok)
+	user.logf("googleauth: cached new token for %q", user.key)
+
+	return nil
+}
+
+// CallbackHandler returns a handler for the redirect URL that completes
+// the flow with HandleCallback for the user session returns, and then
+// calls done, which writes the response, typically a redirect back into
+// the application.
+func (f *WebFlow) CallbackHandler(session func(r *http.Request) string, done func(w http.ResponseWriter, r *http.Request, userID string, err error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := session(r)
+		done(w, r, userID, f.HandleCallback(r, userID))
+	}
+}
+
+// Authenticator returns the Authenticator of the user identified by
+// userID. It never runs a consent flow: until the user has authorized,
+// its Token and Client methods fail with ErrConsentRequired, and the
+// application should send the user to AuthCodeURL.
+func (f *WebFlow) Authenticator(userID string) (*Authenticator, error) {
+	return f.user(userID)
+}
+
+// Client returns an HTTP client authorized as the user identified by
+// userID, as the user's Authenticator does.
+func (f *WebFlow) Client(ctx context.Context, userID string) (*http.Client, error) {
+	a, err := f.user(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.Client(ctx)
+}
+
+func (f *WebFlow) user(userID string) (*Authenticator, error) {
+	if userID == "" || userID == DefaultProfile || strings.Contains(userID, ":") {
+		return nil, fmt.Errorf("googleauth: invalid user ID %q", userID)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if e, ok := f.users[userID]; ok {
+		f.lru.MoveToFront(e)
+		return e.Value.(*webUser).a, nil
+	}
+	u := &webUser{id: userID, a: f.a.withKey(profileKey(userID, f.a.baseKey))}
+	f.users[userID] = f.lru.PushFront(u)
+	if f.lru.Len() > webUsers {
+		e := f.lru.Back()
+		f.lru.Remove(e)
+		delete(f.users, e.Value.(*webUser).id)
+	}
+
+	return u.a, nil
+}
@@ -0,0 +1,92 @@
+package googleauth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func newTestWebFlow(t *testing.T, exchanges *int) *WebFlow {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*exchanges++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"at","refresh_token":"rt","token_type":"Bearer","expires_in":3600}`)
+	}))
+	t.Cleanup(srv.Close)
+	secret := fmt.Sprintf(`{"web":{"client_id":"id","client_secret":"secret",`+
+		`"redirect_uris":["https://app.example/callback"],"token_uri":%q}}`, srv.URL)
+	f, err := NewWebFlow(context.Background(), "", WithSecret([]byte(secret)), WithoutCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f
+}
+
+// callback returns the redirect from the consent page to send userID to.
+func callback(t *testing.T, f *WebFlow, userID string) *http.Request {
+	u, err := f.AuthCodeURL(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := url.Parse(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return httptest.NewRequest("GET", "https://app.example/callback?code=c&state="+p.Query().Get("state"), nil)
+}
+
+func TestWebFlowSession(t *testing.T) {
+	var exchanges int
+	f := newTestWebFlow(t, &exchanges)
+
+	if err := f.HandleCallback(callback(t, f, "alice"), "mallory"); err != errWrongSession {
+		t.Fatalf("HandleCallback from another session = %v, want errWrongSession", err)
+	}
+	if exchanges != 0 {
+		t.Fatal("code exchanged for another session")
+	}
+
+	if err := f.HandleCallback(callback(t, f, "alice"), "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if exchanges != 1 {
+		t.Fatalf("%d exchanges, want 1", exchanges)
+	}
+	a, err := f.Authenticator("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebFlowUsers(t *testing.T) {
+	f := newTestWebFlow(t, new(int))
+	for _, id := range []string{"", DefaultProfile, "a:b"} {
+		if _, err := f.Authenticator(id); err == nil {
+			t.Errorf("user ID %q accepted", id)
+		}
+	}
+
+	first, err := f.Authenticator("user-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= webUsers; i++ {
+		if _, err := f.Authenticator(fmt.Sprintf("user-%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(f.users) != webUsers || f.lru.Len() != webUsers {
+		t.Fatalf("%d users kept, want %d", len(f.users), webUsers)
+	}
+	if a, _ := f.Authenticator("user-0"); a == first {
+		t.Fatal("least recently used user not dropped")
+	}
+}
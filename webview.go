@@ -0,0 +1,43 @@
+package googleauth
+
+import "context"
+
+// An EmbeddedBrowser is a browser window owned by a GUI application, such
+// as a Wails or webview window.
+type EmbeddedBrowser interface {
+	// Navigate loads url in the window.
+	Navigate(url string) error
+	// Close dismisses the window once the flow is over.
+	Close() error
+}
+
+// EmbeddedBrowserReceiver shows the consent page inside an application's
+// own window instead of the system browser. The code is captured with a
+// loopback redirect, so the host application does not need to intercept
+// navigation; the window is closed when the flow ends either way.
+//
+// Google refuses sign-in from some embedded browsers with a
+// disallowed_useragent error; applications should be ready to fall back to
+// a LoopbackReceiver.
+type EmbeddedBrowserReceiver struct {
+	Browser EmbeddedBrowser
+	// Port is the loopback port to listen on. Zero picks a free port.
+	Port int
+}
+
+// ReceiveCode implements CodeReceiver.
+func (r *EmbeddedBrowserReceiver) ReceiveCode(ctx context.Context, req *AuthRequest) (string, error) {
+	defer r.Browser.Close()
+
+	lr := &LoopbackReceiver{Port: r.Port, Opener: navigator{r.Browser}}
+	return lr.ReceiveCode(ctx, req)
+}
+
+// navigator adapts an EmbeddedBrowser to URLOpener.
+type navigator struct {
+	b EmbeddedBrowser
+}
+
+func (n navigator) OpenURL(url string) error {
+	return n.b.Navigate(url)
+}